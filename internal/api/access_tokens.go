@@ -0,0 +1,242 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Scopes limit what a personal access token may do. Access tokens from a
+// login carry every scope.
+const (
+	scopeChirpsRead   = "chirps:read"
+	scopeChirpsWrite  = "chirps:write"
+	scopeProfileRead  = "profile:read"
+	scopeProfileWrite = "profile:write"
+)
+
+var personalAccessTokenScopes = []string{scopeChirpsRead, scopeChirpsWrite, scopeProfileRead, scopeProfileWrite}
+
+// personalAccessTokenPrefix makes tokens easy to recognise, for example by
+// secret scanners, and to tell apart from refresh tokens.
+const personalAccessTokenPrefix = "chirpy_pat_"
+
+var errInsufficientScope = errors.New("token does not have the required scope")
+
+// authorize returns the id of the user making the request. Requests may use
+// an access token, as a bearer token, or a personal access token with the
+// given scope, as an ApiKey.
+func (cfg *ApiConfig) authorize(r *http.Request, scope string) (uuid.UUID, error) {
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return cfg.authenticate(r)
+	}
+
+	token, err := cfg.Db.GetPersonalAccessTokenByHash(r.Context(), auth.HashToken(apiKey, cfg.TOKEN_HASH_KEY))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if token.RevokedAt.Valid {
+		return uuid.Nil, errors.New("personal access token has been revoked")
+	}
+	if token.ExpiresAt.Valid && time.Now().After(token.ExpiresAt.Time) {
+		return uuid.Nil, errors.New("personal access token has expired")
+	}
+	if !slices.Contains(token.Scopes, scope) {
+		return uuid.Nil, errInsufficientScope
+	}
+
+	// Tokens are left alone when their owner is suspended, so they work
+	// again once the suspension is lifted.
+	user, err := cfg.Db.GetUserByID(r.Context(), token.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if user.SuspendedAt.Valid {
+		return uuid.Nil, errUserSuspended
+	}
+
+	err = cfg.Db.TouchPersonalAccessToken(r.Context(), database.TouchPersonalAccessTokenParams{
+		ID:         token.ID,
+		LastUsedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error updating personal access token last use: %v", err)
+	}
+
+	return token.UserID, nil
+}
+
+// viewer returns the id of the user making a request to a public endpoint,
+// or uuid.Nil if the request is anonymous. A token that doesn't authorize
+// reading chirps is ignored rather than rejected, as it would be if it were
+// left out.
+func (cfg *ApiConfig) viewer(r *http.Request) uuid.UUID {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil
+	}
+	userID, err := cfg.authorize(r, scopeChirpsRead)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+func sendAuthorizationErrorResponse(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInsufficientScope) {
+		sendInsufficientScopeResponse(w)
+		return
+	}
+	sendTokenExpiredResponse(w)
+}
+
+func personalAccessTokenFromDB(token database.PersonalAccessToken) PersonalAccessToken {
+	pat := PersonalAccessToken{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+	if token.LastUsedAt.Valid {
+		pat.LastUsedAt = &token.LastUsedAt.Time
+	}
+	if token.ExpiresAt.Valid {
+		pat.ExpiresAt = &token.ExpiresAt.Time
+	}
+	return pat
+}
+
+// Personal access tokens can only be managed with an access token from a
+// login, so a leaked token can't be used to mint more.
+
+func (cfg *ApiConfig) PostAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	params := PersonalAccessTokenParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding personal access token params: %v", err)
+		sendErrorResponse(w, "error creating personal access token")
+		return
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		sendBadRequestResponse(w, "Token name is required")
+		return
+	}
+	if len(params.Scopes) == 0 {
+		sendBadRequestResponse(w, "At least one scope is required")
+		return
+	}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(personalAccessTokenScopes, scope) {
+			sendBadRequestResponse(w, "Unknown scope: "+scope)
+			return
+		}
+	}
+	if params.ExpiresInDays < 0 {
+		sendBadRequestResponse(w, "expires_in_days must not be negative")
+		return
+	}
+
+	secret, err := auth.MakeSecureToken()
+	if err != nil {
+		log.Printf("error generating personal access token: %v", err)
+		sendErrorResponse(w, "error creating personal access token")
+		return
+	}
+	tokenString := personalAccessTokenPrefix + secret
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, params.ExpiresInDays), Valid: true}
+	}
+
+	slices.Sort(params.Scopes)
+	token, err := cfg.Db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(tokenString, cfg.TOKEN_HASH_KEY),
+		Scopes:    slices.Compact(params.Scopes),
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("error saving personal access token: %v", err)
+		sendErrorResponse(w, "error creating personal access token")
+		return
+	}
+
+	// The token itself is only ever returned here.
+	pat := personalAccessTokenFromDB(token)
+	pat.Token = tokenString
+	sendCreatedAccessTokenResponse(w, pat)
+}
+
+func (cfg *ApiConfig) GetAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	tokens, err := cfg.Db.GetPersonalAccessTokensByUserID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting personal access tokens: %v", err)
+		sendErrorResponse(w, "error getting personal access tokens")
+		return
+	}
+
+	pats := []PersonalAccessToken{}
+	for _, token := range tokens {
+		pats = append(pats, personalAccessTokenFromDB(token))
+	}
+
+	sendAccessTokensResponse(w, pats)
+}
+
+func (cfg *ApiConfig) DeleteAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		sendAccessTokenNotFoundResponse(w)
+		return
+	}
+
+	revoked, err := cfg.Db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:        tokenID,
+		UserID:    userID,
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking personal access token: %v", err)
+		sendErrorResponse(w, "error revoking personal access token")
+		return
+	}
+	if revoked == 0 {
+		sendAccessTokenNotFoundResponse(w)
+		return
+	}
+
+	sendAccessTokenRevokedResponse(w)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// DefaultAccountDeletionGrace is how long an account waits between its
+// owner asking for it to be deleted and it being deleted for good.
+const DefaultAccountDeletionGrace = 30 * 24 * time.Hour
+
+// accountDeletionInterval is how often accounts past their grace period are
+// looked for.
+const accountDeletionInterval = time.Hour
+
+// DeleteUserHandler schedules the user's account for deletion. Every token
+// they hold is revoked and their chirps are hidden straight away; logging
+// in again before the grace period ends cancels the deletion.
+func (cfg *ApiConfig) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	params := AccountDeletionParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding account deletion params: %v", err)
+		sendErrorResponse(w, "error deleting account")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendTokenExpiredResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error deleting account")
+		}
+		return
+	}
+
+	if !cfg.confirmPassword(w, r, user, params.Password) {
+		return
+	}
+
+	requestedAt := time.Now()
+	err = cfg.requestUserDeletion(r.Context(), user.ID, requestedAt)
+	if err != nil {
+		log.Printf("error requesting account deletion: %v", err)
+		sendErrorResponse(w, "error deleting account")
+		return
+	}
+
+	err = cfg.revokeUserAccessTokens(r.Context(), user.ID, uuid.Nil)
+	if err != nil {
+		log.Printf("error revoking access tokens: %v", err)
+		sendErrorResponse(w, "error deleting account")
+		return
+	}
+
+	log.Printf("user %v asked for their account to be deleted", user.ID)
+	sendAccountDeletionScheduledResponse(w, AccountDeletion{
+		DeleteAfter: requestedAt.Add(cfg.ACCOUNT_DELETION_GRACE),
+	})
+}
+
+// requestUserDeletion marks the user pending deletion and revokes their
+// refresh tokens and personal access tokens.
+func (cfg *ApiConfig) requestUserDeletion(ctx context.Context, userID uuid.UUID, requestedAt time.Time) error {
+	tx, err := cfg.SqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	err = qtx.RequestUserDeletion(ctx, database.RequestUserDeletionParams{
+		ID:                  userID,
+		UpdatedAt:           requestedAt,
+		DeletionRequestedAt: sql.NullTime{Time: requestedAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = qtx.RevokeRefreshTokensByUserID(ctx, database.RevokeRefreshTokensByUserIDParams{
+		UserID:    userID,
+		UpdatedAt: requestedAt,
+		RevokedAt: sql.NullTime{Time: requestedAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = qtx.RevokePersonalAccessTokensByUserID(ctx, database.RevokePersonalAccessTokensByUserIDParams{
+		UserID:    userID,
+		RevokedAt: sql.NullTime{Time: requestedAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// cancelUserDeletion is called on a successful login. Failing to cancel is
+// logged but does not stop the login.
+func (cfg *ApiConfig) cancelUserDeletion(ctx context.Context, user database.User) {
+	if !user.DeletionRequestedAt.Valid {
+		return
+	}
+
+	err := cfg.Db.CancelUserDeletion(ctx, database.CancelUserDeletionParams{
+		ID:        user.ID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error cancelling account deletion for user %v: %v", user.ID, err)
+		return
+	}
+	log.Printf("login cancelled account deletion for user %v", user.ID)
+}
+
+// RunAccountDeletions deletes accounts whose grace period has ended, checking
+// every accountDeletionInterval until ctx is done. Their chirps, tokens and
+// everything else they own go with them through ON DELETE CASCADE.
+func (cfg *ApiConfig) RunAccountDeletions(ctx context.Context) {
+	ticker := time.NewTicker(accountDeletionInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := cfg.Db.DeleteUsersPendingDeletion(ctx, sql.NullTime{
+			Time:  time.Now().Add(-cfg.ACCOUNT_DELETION_GRACE),
+			Valid: true,
+		})
+		if err != nil {
+			log.Printf("error deleting accounts: %v", err)
+		} else if deleted > 0 {
+			log.Printf("deleted %d accounts past their grace period", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
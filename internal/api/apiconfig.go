@@ -1,14 +1,43 @@
 package api
 
 import (
+	"database/sql"
 	"sync/atomic"
+	"time"
 
+	"github.com/crisp-coder/chirpy/internal/auth"
 	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/crisp-coder/chirpy/internal/mail"
+	"github.com/crisp-coder/chirpy/internal/oidc"
 )
 
 type ApiConfig struct {
 	Db             *database.Queries
-	JWT_SECRET     string
+	SqlDB          *sql.DB
+	JWTKeys        *auth.Keyring
+	Denylist       auth.Denylist
+	Passwords      *auth.PasswordHasher
+	PasswordPolicy *auth.PasswordPolicy
 	POLKA_KEY      string
-	FileserverHits atomic.Int32
+	TOKEN_HASH_KEY string
+	APP_BASE_URL   string
+	PLATFORM       string
+	Mailer         mail.Mailer
+
+	// OIDC is the identity provider users can log in through, or nil if
+	// single sign-on is not configured.
+	OIDC *oidc.Provider
+
+	// ACCOUNT_DELETION_GRACE is how long deleted accounts can still be
+	// recovered by logging in.
+	ACCOUNT_DELETION_GRACE time.Duration
+
+	// CHIRP_EDIT_WINDOW is how long after posting a chirp its author can
+	// still edit it.
+	CHIRP_EDIT_WINDOW time.Duration
+
+	REQUIRE_VERIFIED_EMAIL bool
+	FileserverHits         atomic.Int32
+
+	loginLimiter loginLimiter
 }
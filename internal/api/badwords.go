@@ -2,6 +2,25 @@ package api
 
 import "strings"
 
+// maxChirpLength is the longest chirp body that can be posted.
+const maxChirpLength = 140
+
+// badWords are censored out of every chirp body.
+var badWords = []string{
+	"kerfuffle",
+	"sharbert",
+	"fornax",
+}
+
+// cleanChirpBody censors body before it is saved. It reports false if body
+// is too long to be a chirp.
+func cleanChirpBody(body string) (string, bool) {
+	if len(body) > maxChirpLength {
+		return "", false
+	}
+	return StripBadWords(body, "****", badWords), true
+}
+
 func StripBadWords(s string, r string, badWords []string) string {
 	words := strings.Split(s, " ")
 	for i := range words {
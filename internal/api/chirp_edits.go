@@ -0,0 +1,171 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// DefaultChirpEditWindow is how long after posting a chirp its author can
+// still edit it.
+const DefaultChirpEditWindow = 15 * time.Minute
+
+// PatchChirpHandler replaces the body of one of the user's chirps, as long as
+// it is still inside the edit window. The old body is kept as a revision.
+func (cfg *ApiConfig) PatchChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	params := ChirpEditParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding chirp edit params: %v", err)
+		sendBadRequestResponse(w, "Request body is not valid JSON")
+		return
+	}
+
+	body, ok := cleanChirpBody(params.Body)
+	if !ok {
+		sendChirpTooLong(w)
+		return
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting chirp edit transaction: %v", err)
+		sendErrorResponse(w, "error editing chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	// The row stays locked until commit so concurrent edits each record
+	// the body they replaced.
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendChirpNotFoundResponse(w)
+		} else {
+			log.Printf("error getting chirp from database: %v", err)
+			sendErrorResponse(w, "error editing chirp")
+		}
+		return
+	}
+
+	if chirp.UserID != userID {
+		sendUserForbiddenResponse(w)
+		return
+	}
+
+	if chirp.RechirpOfID.Valid {
+		sendBadRequestResponse(w, "Rechirps have no body to edit")
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(chirp.CreatedAt) > cfg.CHIRP_EDIT_WINDOW {
+		sendChirpEditWindowClosedResponse(w)
+		return
+	}
+
+	if body != chirp.Body {
+		err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ID:         uuid.New(),
+			ChirpID:    chirp.ID,
+			Body:       chirp.Body,
+			CreatedAt:  chirp.UpdatedAt,
+			ReplacedAt: now,
+		})
+		if err != nil {
+			log.Printf("error saving chirp revision: %v", err)
+			sendErrorResponse(w, "error editing chirp")
+			return
+		}
+
+		chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:        chirp.ID,
+			Body:      body,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			log.Printf("error updating chirp: %v", err)
+			sendErrorResponse(w, "error editing chirp")
+			return
+		}
+
+		err = setChirpHashtags(r.Context(), qtx, chirp)
+		if err != nil {
+			log.Printf("error saving hashtags: %v", err)
+			sendErrorResponse(w, "error editing chirp")
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing chirp edit: %v", err)
+		sendErrorResponse(w, "error editing chirp")
+		return
+	}
+
+	api_chirp, err := cfg.chirpFromDB(r.Context(), userID, chirp)
+	if err != nil {
+		log.Printf("error getting chirp author: %v", err)
+		sendErrorResponse(w, "error editing chirp")
+		return
+	}
+	sendChirpResponse(w, api_chirp)
+}
+
+// GetChirpRevisionsHandler lists the bodies a chirp had before its edits,
+// most recent first.
+func (cfg *ApiConfig) GetChirpRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	chirp, err := cfg.Db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendChirpNotFoundResponse(w)
+		} else {
+			log.Printf("error getting chirp from database: %v", err)
+			sendErrorResponse(w, "error getting chirp revisions")
+		}
+		return
+	}
+
+	dbRevisions, err := cfg.Db.GetChirpRevisions(r.Context(), chirp.ID)
+	if err != nil {
+		log.Printf("error getting chirp revisions from database: %v", err)
+		sendErrorResponse(w, "error getting chirp revisions")
+		return
+	}
+
+	revisions := make([]ChirpRevision, len(dbRevisions))
+	for i, revision := range dbRevisions {
+		revisions[i] = ChirpRevision{
+			ID:         revision.ID,
+			Body:       revision.Body,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+		}
+	}
+	sendChirpRevisionsResponse(w, revisions)
+}
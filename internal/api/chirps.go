@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpsFromDB converts chirps to their API model along with their authors'
+// profiles, their like, rechirp and quote counts and the chirps they rechirp
+// or quote. Deleted chirps and chirps whose author is pending deletion become
+// tombstones. LikedByMe is set for the chirps viewerID liked; it is uuid.Nil
+// for anonymous requests.
+func (cfg *ApiConfig) chirpsFromDB(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]Chirp, error) {
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOfID.Valid {
+			originalIDs = append(originalIDs, chirp.RechirpOfID.UUID)
+		}
+		if chirp.QuoteOfID.Valid {
+			originalIDs = append(originalIDs, chirp.QuoteOfID.UUID)
+		}
+	}
+	originals := []database.Chirp{}
+	if len(originalIDs) > 0 {
+		var err error
+		originals, err = cfg.Db.GetChirpsByIDs(ctx, originalIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	all := make([]database.Chirp, 0, len(chirps)+len(originals))
+	all = append(all, chirps...)
+	all = append(all, originals...)
+	profiles, err := cfg.authorProfiles(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := cfg.chirpCounts(ctx, viewerID, all)
+	if err != nil {
+		return nil, err
+	}
+
+	convert := func(chirp database.Chirp) Chirp {
+		api_chirp := Chirp{
+			ID:          chirp.ID,
+			CreatedAt:   chirp.CreatedAt,
+			UpdatedAt:   chirp.UpdatedAt,
+			InReplyTo:   nullUUIDPtr(chirp.ParentChirpID),
+			RootChirpID: nullUUIDPtr(chirp.RootChirpID),
+		}
+		profile, ok := profiles[chirp.UserID]
+		if chirp.DeletedAt.Valid || !ok {
+			api_chirp.Deleted = true
+			return api_chirp
+		}
+		api_chirp.Body = chirp.Body
+		api_chirp.UserID = chirp.UserID
+		api_chirp.Author = &profile
+		api_chirp.LikeCount = counts[chirp.ID].LikeCount
+		api_chirp.LikedByMe = counts[chirp.ID].LikedByMe
+		api_chirp.RechirpCount = counts[chirp.ID].RechirpCount
+		api_chirp.QuoteCount = counts[chirp.ID].QuoteCount
+		return api_chirp
+	}
+
+	embedded := make(map[uuid.UUID]Chirp, len(originals))
+	for _, original := range originals {
+		embedded[original.ID] = convert(original)
+	}
+
+	api_chirps := make([]Chirp, len(chirps))
+	for i, chirp := range chirps {
+		api_chirps[i] = convert(chirp)
+		if api_chirps[i].Deleted {
+			continue
+		}
+		if original, ok := embedded[chirp.RechirpOfID.UUID]; ok && chirp.RechirpOfID.Valid {
+			api_chirps[i].RechirpOf = &original
+		}
+		if original, ok := embedded[chirp.QuoteOfID.UUID]; ok && chirp.QuoteOfID.Valid {
+			api_chirps[i].QuoteOf = &original
+		}
+	}
+	return api_chirps, nil
+}
+
+// chirpCounts loads how many times each of chirps has been liked, rechirped
+// and quoted, and whether viewerID liked it, keyed by chirp id.
+func (cfg *ApiConfig) chirpCounts(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) (map[uuid.UUID]database.GetChirpCountsRow, error) {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	rows, err := cfg.Db.GetChirpCounts(ctx, database.GetChirpCountsParams{
+		Ids:      ids,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]database.GetChirpCountsRow, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row
+	}
+	return counts, nil
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func (cfg *ApiConfig) chirpFromDB(ctx context.Context, viewerID uuid.UUID, chirp database.Chirp) (Chirp, error) {
+	api_chirps, err := cfg.chirpsFromDB(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return Chirp{}, err
+	}
+	return api_chirps[0], nil
+}
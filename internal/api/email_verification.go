@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	chirpymail "github.com/crisp-coder/chirpy/internal/mail"
+)
+
+const emailVerificationTokenDuration = 48 * time.Hour
+
+// validEmail reports whether s is a bare email address such as
+// "user@example.com", without a display name or angle brackets.
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// sendVerificationEmail emails the user a link that confirms they own their
+// current email address.
+func (cfg *ApiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeSecureToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.Db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token, cfg.TOKEN_HASH_KEY),
+		UserID:    user.ID,
+		Email:     user.Email,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(emailVerificationTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.Mailer.Send(ctx, chirpymail.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Confirm this is your email address by opening this link:\n%s/api/users/verify?token=%s\n",
+			cfg.APP_BASE_URL, url.QueryEscape(token)),
+	})
+}
+
+func (cfg *ApiConfig) GetVerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		sendInvalidVerificationTokenResponse(w)
+		return
+	}
+
+	verification, err := cfg.Db.UseEmailVerificationToken(r.Context(), database.UseEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token, cfg.TOKEN_HASH_KEY),
+		ExpiresAt: time.Now(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendInvalidVerificationTokenResponse(w)
+		} else {
+			log.Printf("error using email verification token: %v", err)
+			sendErrorResponse(w, "error verifying email")
+		}
+		return
+	}
+
+	// The token only proves ownership of the address it was sent to. If the
+	// user has changed their email since, nothing is verified.
+	verified, err := cfg.Db.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:              verification.UserID,
+		UpdatedAt:       time.Now(),
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		Email:           verification.Email,
+	})
+	if err != nil {
+		log.Printf("error verifying user email: %v", err)
+		sendErrorResponse(w, "error verifying email")
+		return
+	}
+	if verified == 0 {
+		sendInvalidVerificationTokenResponse(w)
+		return
+	}
+
+	sendEmailVerifiedResponse(w)
+}
+
+func (cfg *ApiConfig) PostResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeProfileWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error sending verification email")
+		}
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		sendEmailVerifiedResponse(w)
+		return
+	}
+
+	err = cfg.sendVerificationEmail(r.Context(), user)
+	if err != nil {
+		log.Printf("error sending verification email: %v", err)
+		sendErrorResponse(w, "error sending verification email")
+		return
+	}
+
+	sendVerificationEmailSentResponse(w)
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// PostFollowHandler makes the user follow another user, given by id or
+// handle. Following someone twice, or unfollowing someone not followed,
+// changes nothing.
+func (cfg *ApiConfig) PostFollowHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeProfileWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	followee, err := cfg.userByIDOrHandle(r.Context(), r.PathValue("user"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error following user")
+		}
+		return
+	}
+
+	if followee.ID == userID {
+		sendBadRequestResponse(w, "You can't follow yourself")
+		return
+	}
+
+	err = cfg.Db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("error following user: %v", err)
+		sendErrorResponse(w, "error following user")
+		return
+	}
+
+	sendFollowUpdatedResponse(w)
+}
+
+func (cfg *ApiConfig) DeleteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeProfileWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	followee, err := cfg.userByIDOrHandle(r.Context(), r.PathValue("user"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error unfollowing user")
+		}
+		return
+	}
+
+	err = cfg.Db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		log.Printf("error unfollowing user: %v", err)
+		sendErrorResponse(w, "error unfollowing user")
+		return
+	}
+
+	sendFollowUpdatedResponse(w)
+}
+
+// GetFollowersHandler lists the users following a user a page at a time,
+// most recent follow first.
+func (cfg *ApiConfig) GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	user, p, cursorCreatedAt, cursorID, ok := cfg.parseFollowListing(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.Db.ListFollowers(r.Context(), database.ListFollowersParams{
+		FolloweeID:      user.ID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting followers from database: %v", err)
+		sendErrorResponse(w, "error getting followers")
+		return
+	}
+
+	sendFollowPage(w, r, p, rows)
+}
+
+// GetFollowingHandler lists the users a user follows a page at a time, most
+// recent follow first.
+func (cfg *ApiConfig) GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	user, p, cursorCreatedAt, cursorID, ok := cfg.parseFollowListing(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.Db.ListFollowing(r.Context(), database.ListFollowingParams{
+		FollowerID:      user.ID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting followed users from database: %v", err)
+		sendErrorResponse(w, "error getting followed users")
+		return
+	}
+
+	// Both listings return the same columns, so their rows convert.
+	followers := make([]database.ListFollowersRow, len(rows))
+	for i, row := range rows {
+		followers[i] = database.ListFollowersRow(row)
+	}
+	sendFollowPage(w, r, p, followers)
+}
+
+// parseFollowListing reads the user and page of a follower or following
+// listing. Follows are paged by (created_at, user id) with the same cursor
+// chirp listings use. If the request is invalid the response has already
+// been sent.
+func (cfg *ApiConfig) parseFollowListing(w http.ResponseWriter, r *http.Request) (database.User, page, sql.NullTime, uuid.UUID, bool) {
+	user, err := cfg.userByIDOrHandle(r.Context(), r.PathValue("user"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error getting follows")
+		}
+		return database.User{}, page{}, sql.NullTime{}, uuid.Nil, false
+	}
+
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return database.User{}, page{}, sql.NullTime{}, uuid.Nil, false
+	}
+
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return database.User{}, page{}, sql.NullTime{}, uuid.Nil, false
+	}
+
+	return user, p, cursorCreatedAt, cursorID, true
+}
+
+func sendFollowPage(w http.ResponseWriter, r *http.Request, p page, rows []database.ListFollowersRow) {
+	var nextCursor *string
+	if len(rows) > p.Limit {
+		rows = rows[:p.Limit]
+		last := rows[len(rows)-1]
+		cursor := chirpCursor{CreatedAt: last.FollowedAt, ID: last.User.ID}.String()
+		nextCursor = &cursor
+		setNextPageLink(w, r, cursor)
+	}
+
+	follows := make([]Follow, len(rows))
+	for i, row := range rows {
+		follows[i] = Follow{
+			Profile:    profileFromDB(row.User),
+			FollowedAt: row.FollowedAt,
+		}
+	}
+	sendFollowsResponse(w, FollowPage{
+		Users:      follows,
+		NextCursor: nextCursor,
+	})
+}
+
+// GetTimelineHandler lists chirps, including rechirps, from the users the
+// user follows a page at a time, newest first.
+func (cfg *ApiConfig) GetTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeChirpsRead)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	chirps, err := cfg.Db.ListTimeline(r.Context(), database.ListTimelineParams{
+		FollowerID:      userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting timeline from database: %v", err)
+		sendErrorResponse(w, "error getting timeline")
+		return
+	}
+
+	cfg.sendChirpPage(w, r, p, chirps, func(i int) string {
+		return chirpCursor{CreatedAt: chirps[i].CreatedAt, ID: chirps[i].ID}.String()
+	})
+}
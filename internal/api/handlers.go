@@ -1,13 +1,14 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
@@ -16,6 +17,8 @@ import (
 	"github.com/google/uuid"
 )
 
+const refreshTokenDuration = 60 * 24 * time.Hour
+
 func (cfg *ApiConfig) AppHandler() http.Handler {
 	fileserver := http.FileServer(http.Dir("public"))
 	app_handler := middlewareLog(cfg.middlewareMetricsInc(fileserver))
@@ -87,18 +90,9 @@ func (cfg *ApiConfig) PostPolkaWebhookHandler(w http.ResponseWriter, r *http.Req
 }
 
 func (cfg *ApiConfig) DeleteChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
-
-	accessToken, err := auth.GetBearerToken(r.Header)
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
 	if err != nil {
-		log.Println("bearer token not found: %w", err)
-		sendTokenExpiredResponse(w)
-		return
-	}
-
-	userID, err := auth.ValidateJWT(accessToken, cfg.JWT_SECRET)
-	if err != nil {
-		log.Println("access token expired: %w", err)
-		sendTokenExpiredResponse(w)
+		sendAuthorizationErrorResponse(w, err)
 		return
 	}
 
@@ -137,75 +131,53 @@ func (cfg *ApiConfig) DeleteChirpByIDHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = cfg.Db.DeleteChirpByID(r.Context(), chirp.ID)
+	err = cfg.deleteChirp(r.Context(), chirp.ID)
 	if err != nil {
 		log.Println("error deleting chirp from database: %w", err)
 		sendErrorResponse(w, "error deleting chirp")
+		return
 	}
 
 	sendChirpDeletedResponse(w)
 }
 
+// PutUsersHandler replaces the user's email and password. It needs the
+// current password, and personal access tokens can't be used. The password
+// is only re-hashed, and other sessions ended, when it actually changes.
 func (cfg *ApiConfig) PutUsersHandler(w http.ResponseWriter, r *http.Request) {
-	accessToken, err := auth.GetBearerToken(r.Header)
+	userId, err := cfg.authenticate(r)
 	if err != nil {
-		log.Println("missing bearer token in headers: %w", err)
 		sendTokenExpiredResponse(w)
 		return
 	}
 
-	userId, err := auth.ValidateJWT(accessToken, cfg.JWT_SECRET)
-	if err != nil {
-		log.Println("error updating user: %w", err)
-		sendTokenExpiredResponse(w)
-		return
-	}
-
-	temp_user := User{}
+	params := UserPutParams{}
 	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&temp_user)
+	err = decoder.Decode(&params)
 	if err != nil {
 		log.Println("error decoding update user params: %w", err)
 		sendTokenExpiredResponse(w)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(temp_user.Password)
-	if err != nil {
-		log.Println("Error hashing password: %w", err)
-		sendErrorResponse(w, "error logging in")
+	if !validEmail(params.Email) {
+		sendInvalidEmailResponse(w)
 		return
 	}
 
-	user, err := cfg.Db.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userId,
-		UpdatedAt:      time.Now(),
-		Email:          temp_user.Email,
-		HashedPassword: hashedPassword,
-	})
-	if err != nil {
-		if err == sql.ErrNoRows {
-			sendUserNotFoundResponse(w)
-		} else {
-			log.Println("error querying update user: %w", err)
-			sendErrorResponse(w, "error updating user")
-		}
-		return
+	update := UserPatchParams{
+		Email:           &params.Email,
+		CurrentPassword: params.CurrentPassword,
 	}
-
-	api_user := User{}
-	api_user.ID = user.ID
-	api_user.CreatedAt = user.CreatedAt
-	api_user.UpdatedAt = user.UpdatedAt
-	api_user.Email = user.Email
-	api_user.Password = temp_user.Password
-
-	sendUpdatedUser(w, api_user)
+	if params.Password != params.CurrentPassword {
+		update.Password = &params.Password
+	}
+	cfg.updateUser(w, r, userId, update)
 }
 
 func (cfg *ApiConfig) PostUsersHandler(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
-	temp_user := User{}
+	temp_user := UserParams{}
 	err := decoder.Decode(&temp_user)
 
 	if err != nil {
@@ -214,7 +186,29 @@ func (cfg *ApiConfig) PostUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashed_password, err := auth.HashPassword(temp_user.Password)
+	if !validEmail(temp_user.Email) {
+		sendInvalidEmailResponse(w)
+		return
+	}
+
+	if !cfg.acceptablePassword(w, temp_user.Password, temp_user.Email) {
+		return
+	}
+
+	handle, ok := normalizeHandle(temp_user.Handle)
+	if temp_user.Handle == "" {
+		handle, err = generateHandle()
+		if err != nil {
+			log.Printf("error generating handle: %v", err)
+			sendErrorResponse(w, "error creating user")
+			return
+		}
+	} else if !ok {
+		sendInvalidHandleResponse(w)
+		return
+	}
+
+	hashed_password, err := cfg.Passwords.Hash(temp_user.Password)
 	if err != nil {
 		log.Println("Error hashing password: %w", err)
 		sendErrorResponse(w, "error logging in")
@@ -227,23 +221,25 @@ func (cfg *ApiConfig) PostUsersHandler(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:      time.Now(),
 		Email:          temp_user.Email,
 		HashedPassword: hashed_password,
+		Handle:         handle,
 	})
 
 	if err != nil {
+		if isUniqueViolation(err, "users_handle_key") {
+			sendHandleInUseResponse(w)
+			return
+		}
 		log.Println("error creating user: %w", err)
 		sendErrorResponse(w, "error logging in")
 		return
 	}
 
-	api_user := User{
-		ID:        user.ID,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Email:     user.Email,
-		Password:  temp_user.Password,
+	err = cfg.sendVerificationEmail(r.Context(), user)
+	if err != nil {
+		log.Printf("error sending verification email: %v", err)
 	}
 
-	sendUserCreated(w, api_user)
+	sendUserCreated(w, userFromDB(user))
 }
 
 func (cfg *ApiConfig) PostLoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -256,65 +252,94 @@ func (cfg *ApiConfig) PostLoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := clientIP(r)
+	if retryAfter := cfg.loginLimiter.retryAfter(ip, time.Now()); retryAfter > 0 {
+		sendTooManyLoginAttemptsResponse(w, retryAfter)
+		return
+	}
+
+	// Unknown emails and wrong passwords get the same response, take the
+	// same time and lock out the same way, so logins can't be used to find
+	// registered emails. The password is always hashed before the lockout
+	// is checked for the same reason.
 	user, err := cfg.Db.GetUserByEmail(r.Context(), loginParams.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			sendUserNotFoundResponse(w)
-		} else {
-			log.Println(err)
+		if err != sql.ErrNoRows {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error logging in")
+			return
+		}
+		cfg.Passwords.VerifyDummy(loginParams.Password)
+		retryAfter, err := cfg.unknownEmailRetryAfter(r.Context(), loginParams.Email, time.Now())
+		if err != nil {
+			log.Printf("error getting failed logins from database: %v", err)
+			sendErrorResponse(w, "error logging in")
+			return
+		}
+		if retryAfter > 0 {
+			sendTooManyLoginAttemptsResponse(w, retryAfter)
+			return
+		}
+		err = cfg.recordUnknownEmailFailure(r.Context(), ip, loginParams.Email)
+		if err != nil {
+			log.Printf("error recording failed login: %v", err)
 		}
+		sendInvalidCredentialsResponse(w)
 		return
 	}
 
-	err = auth.CheckPasswordHash(loginParams.Password, user.HashedPassword)
+	needsRehash, err := cfg.Passwords.Verify(loginParams.Password, user.HashedPassword)
+	if retryAfter := accountRetryAfter(user, time.Now()); retryAfter > 0 {
+		sendTooManyLoginAttemptsResponse(w, retryAfter)
+		return
+	}
 	if err != nil {
-		log.Println("failed to match password: %w", err)
-		sendIncorrectPasswordResponse(w)
+		if err != auth.ErrPasswordMismatch {
+			log.Printf("error verifying password: %v", err)
+		}
+		err = cfg.recordFailedLogin(r.Context(), ip, user)
+		if err != nil {
+			log.Printf("error recording failed login: %v", err)
+		}
+		sendInvalidCredentialsResponse(w)
 		return
 	}
 
-	jwtExpiry := loginParams.ExpiresInSeconds
-	if jwtExpiry > 3600 || jwtExpiry == 0 {
-		jwtExpiry = 3600
+	if needsRehash {
+		cfg.rehashPassword(r.Context(), user, loginParams.Password)
 	}
 
-	refToken, err := auth.MakeRefreshToken()
-	if err != nil {
-		log.Println("error creating refresh token: %w", err)
-		sendErrorResponse(w, "error logging in")
+	if user.SuspendedAt.Valid {
+		sendUserSuspendedResponse(w)
+		return
 	}
 
-	duration60Days := time.Hour * time.Duration(24*60)
-
-	api_refToken, err := cfg.Db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refToken,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user.ID,
-		ExpiresAt: sql.NullTime{Valid: true, Time: time.Now().Add(duration60Days)},
-	})
+	if user.TotpEnabledAt.Valid {
+		cfg.sendTwoFactorChallenge(w, user)
+		return
+	}
 
+	err = cfg.recordSuccessfulLogin(r.Context(), user)
 	if err != nil {
-		log.Println("error inserting refresh token into database: %w", err)
-		sendErrorResponse(w, "error logging in")
+		log.Printf("error clearing failed logins: %v", err)
+	}
+	cfg.cancelUserDeletion(r.Context(), user)
+
+	jwtExpiry := loginParams.ExpiresInSeconds
+	if jwtExpiry > 3600 || jwtExpiry == 0 {
+		jwtExpiry = 3600
 	}
 
-	jwtToken, err := auth.MakeJWT(user.ID, cfg.JWT_SECRET, time.Duration(jwtExpiry)*time.Second)
+	jwtToken, refToken, err := cfg.startSession(r, user, time.Duration(jwtExpiry)*time.Second)
 	if err != nil {
-		log.Println("error creating jwt: %w", err)
+		log.Printf("error starting session: %v", err)
 		sendErrorResponse(w, "error logging in")
+		return
 	}
 
-	api_user := User{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		Password:     loginParams.Password,
-		Token:        jwtToken,
-		RefreshToken: api_refToken.Token,
-		IsChirpyRed:  user.IsChirpyRed.Bool,
-	}
+	api_user := userFromDB(user)
+	api_user.Token = jwtToken
+	api_user.RefreshToken = refToken
 
 	sendLoginAccepted(w, api_user)
 }
@@ -327,7 +352,7 @@ func (cfg *ApiConfig) PostRefreshHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	refreshToken, err := cfg.Db.GetRefreshTokenByToken(r.Context(), token)
+	refreshToken, err := cfg.Db.GetRefreshTokenByHash(r.Context(), auth.HashToken(token, cfg.TOKEN_HASH_KEY))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sendTokenExpiredResponse(w)
@@ -338,7 +363,18 @@ func (cfg *ApiConfig) PostRefreshHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if time.Now().After(refreshToken.ExpiresAt.Time) || refreshToken.RevokedAt.Valid {
+	if refreshToken.RevokedAt.Valid {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	if refreshToken.RotatedAt.Valid {
+		cfg.revokeReusedRefreshToken(r.Context(), refreshToken)
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt.Time) {
 		sendTokenExpiredResponse(w)
 		return
 	}
@@ -354,20 +390,145 @@ func (cfg *ApiConfig) PostRefreshHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	jwtToken, err := auth.MakeJWT(user.ID, cfg.JWT_SECRET, time.Duration(1)*time.Hour)
+	if user.SuspendedAt.Valid {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting refresh transaction: %v", err)
+		sendErrorResponse(w, "error refreshing token")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	// Only one caller can rotate a given token. Losing the race means the
+	// token was presented twice, which is treated the same as reuse.
+	_, err = qtx.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		TokenHash: refreshToken.TokenHash,
+		UpdatedAt: time.Now(),
+		RotatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			cfg.revokeReusedRefreshToken(r.Context(), refreshToken)
+			sendTokenExpiredResponse(w)
+		} else {
+			log.Printf("error rotating refresh token: %v", err)
+			sendErrorResponse(w, "error refreshing token")
+		}
+		return
+	}
+
+	jwtToken, newRefreshToken, err := cfg.createSessionTokens(r, qtx, database.CreateRefreshTokenParams{
+		UserID:           user.ID,
+		FamilyID:         refreshToken.FamilyID,
+		SessionStartedAt: refreshToken.SessionStartedAt,
+		ExpiresAt:        refreshToken.ExpiresAt,
+	}, time.Hour)
 	if err != nil {
-		log.Println("error refreshing token: %w", err)
+		log.Printf("error creating session tokens: %v", err)
+		sendErrorResponse(w, "error refreshing token")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing refresh transaction: %v", err)
 		sendErrorResponse(w, "error refreshing token")
 		return
 	}
 
 	accessToken := AccessToken{
-		Token: jwtToken,
+		Token:        jwtToken,
+		RefreshToken: newRefreshToken,
 	}
 
 	sendAccessTokenResponse(w, accessToken)
 }
 
+// revokeReusedRefreshToken is called when a refresh token that has already
+// been rotated out is presented again. The token has most likely leaked, so
+// every token in its family is revoked and the holder has to log in again.
+func (cfg *ApiConfig) revokeReusedRefreshToken(ctx context.Context, refreshToken database.RefreshToken) {
+	log.Printf("refresh token reuse detected for user %s, revoking token family %s",
+		refreshToken.UserID, refreshToken.FamilyID)
+
+	err := cfg.Db.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+		FamilyID:  refreshToken.FamilyID,
+		UpdatedAt: time.Now(),
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking refresh token family %s: %v", refreshToken.FamilyID, err)
+	}
+
+	err = cfg.revokeSessionAccessTokens(ctx, refreshToken.FamilyID)
+	if err != nil {
+		log.Printf("error revoking access tokens for family %s: %v", refreshToken.FamilyID, err)
+	}
+}
+
+// rehashPassword replaces the user's password hash with one made by the
+// current hasher. It is called after a successful login while the plaintext
+// password is at hand; failing to upgrade the hash is not a login failure.
+func (cfg *ApiConfig) rehashPassword(ctx context.Context, user database.User, password string) {
+	hashedPassword, err := cfg.Passwords.Hash(password)
+	if err != nil {
+		log.Printf("error rehashing password: %v", err)
+		return
+	}
+
+	err = cfg.Db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:             user.ID,
+		UpdatedAt:      time.Now(),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("error saving rehashed password: %v", err)
+	}
+}
+
+// finishLogin starts a session for user and responds with its tokens.
+func (cfg *ApiConfig) finishLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.SuspendedAt.Valid {
+		sendUserSuspendedResponse(w)
+		return
+	}
+	cfg.cancelUserDeletion(r.Context(), user)
+
+	jwtToken, refToken, err := cfg.startSession(r, user, time.Hour)
+	if err != nil {
+		log.Printf("error starting session: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+
+	api_user := userFromDB(user)
+	api_user.Token = jwtToken
+	api_user.RefreshToken = refToken
+
+	sendLoginAccepted(w, api_user)
+}
+
+// startSession starts a new refresh token family for user and returns its
+// first refresh token along with an access token.
+func (cfg *ApiConfig) startSession(r *http.Request, user database.User, accessTokenDuration time.Duration) (accessToken string, refreshToken string, err error) {
+	accessToken, refreshToken, err = cfg.createSessionTokens(r, cfg.Db, database.CreateRefreshTokenParams{
+		UserID:           user.ID,
+		FamilyID:         uuid.New(),
+		SessionStartedAt: time.Now(),
+		ExpiresAt:        sql.NullTime{Valid: true, Time: time.Now().Add(refreshTokenDuration)},
+	}, accessTokenDuration)
+	if err != nil {
+		return "", "", fmt.Errorf("error creating session tokens: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
 func (cfg *ApiConfig) PostRevokeHandler(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -376,7 +537,7 @@ func (cfg *ApiConfig) PostRevokeHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	refreshToken, err := cfg.Db.GetRefreshTokenByToken(r.Context(), token)
+	refreshToken, err := cfg.Db.GetRefreshTokenByHash(r.Context(), auth.HashToken(token, cfg.TOKEN_HASH_KEY))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sendTokenExpiredResponse(w)
@@ -385,17 +546,24 @@ func (cfg *ApiConfig) PostRevokeHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
-	err = cfg.Db.SetRefreshTokenRevoked(
-		r.Context(),
-		database.SetRefreshTokenRevokedParams{
-			Token:     refreshToken.Token,
-			UpdatedAt: time.Now(),
-			RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
-		})
+	// Logging out ends the whole session, including any access tokens
+	// issued to it that have not expired yet.
+	err = cfg.Db.RevokeRefreshTokenFamily(r.Context(), database.RevokeRefreshTokenFamilyParams{
+		FamilyID:  refreshToken.FamilyID,
+		UpdatedAt: time.Now(),
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking token in database: %v", err)
+		sendErrorResponse(w, "error revoking refresh token")
+		return
+	}
 
+	err = cfg.revokeSessionAccessTokens(r.Context(), refreshToken.FamilyID)
 	if err != nil {
-		log.Println("error revoking token in database: %w", err)
+		log.Printf("error revoking access tokens: %v", err)
 		sendErrorResponse(w, "error revoking refresh token")
+		return
 	}
 
 	sendRefreshTokenRevokedResponse(w)
@@ -420,61 +588,72 @@ func (cfg *ApiConfig) GetChirpByIDHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	api_Chirp := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	api_Chirp, err := cfg.chirpFromDB(r.Context(), cfg.viewer(r), chirp)
+	if err != nil {
+		log.Printf("error getting chirp author: %v", err)
+		sendErrorResponse(w, "error getting chirp")
+		return
 	}
 	sendChirpResponse(w, api_Chirp)
 }
 
+// GetChirpsHandler lists chirps a page at a time, oldest first unless
+// sort=desc is given.
 func (cfg *ApiConfig) GetChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	authorID := r.URL.Query().Get("author_id")
-	sort_p := r.URL.Query().Get("sort")
-	var chirps []database.Chirp
-	var err error
-	if authorID != "" {
-		userID, err := uuid.Parse(authorID)
+	query := r.URL.Query()
+	p, err := parsePage(query)
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if author := query.Get("author_id"); author != "" {
+		authorID.UUID, err = uuid.Parse(author)
 		if err != nil {
 			log.Println("error parsing author id")
 			sendErrorResponse(w, "error getting chirps")
 			return
 		}
-		chirps, err = cfg.Db.GetChirpsByUserID(r.Context(), userID)
-		if err != nil {
-			log.Println(err)
-			sendErrorResponse(w, err.Error())
-			return
-		}
-	} else {
-		chirps, err = cfg.Db.GetChirps(r.Context())
-		if err != nil {
-			log.Println(err)
-			sendErrorResponse(w, err.Error())
-			return
-		}
+		authorID.Valid = true
 	}
 
-	if strings.ToLower(sort_p) == "asc" || sort_p == "" {
-		sort.Slice(chirps, func(i int, j int) bool { return chirps[i].CreatedAt.Before(chirps[j].CreatedAt) })
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
 	}
 
-	api_Chirp := make([]Chirp, len(chirps))
-	for i := range chirps {
-		api_Chirp[i].ID = chirps[i].ID
-		api_Chirp[i].CreatedAt = chirps[i].CreatedAt
-		api_Chirp[i].UpdatedAt = chirps[i].UpdatedAt
-		api_Chirp[i].Body = chirps[i].Body
-		api_Chirp[i].UserID = chirps[i].UserID
+	var chirps []database.Chirp
+	if strings.ToLower(query.Get("sort")) == "desc" {
+		chirps, err = cfg.Db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           p.fetchLimit(),
+		})
+	} else {
+		chirps, err = cfg.Db.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           p.fetchLimit(),
+		})
 	}
-	sendChirpsResponse(w, api_Chirp)
+	if err != nil {
+		log.Printf("error getting chirps from database: %v", err)
+		sendErrorResponse(w, "error getting chirps")
+		return
+	}
+
+	cfg.sendChirpPage(w, r, p, chirps, func(i int) string {
+		return chirpCursor{CreatedAt: chirps[i].CreatedAt, ID: chirps[i].ID}.String()
+	})
 }
 
 func (cfg *ApiConfig) PostChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
-	chirp := Chirp{}
+	chirp := ChirpParams{}
 	err := decoder.Decode(&chirp)
 
 	if err != nil {
@@ -483,29 +662,15 @@ func (cfg *ApiConfig) PostChirpsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(chirp.Body) > 140 {
+	cleaned_body, ok := cleanChirpBody(chirp.Body)
+	if !ok {
 		sendChirpTooLong(w)
 		return
 	}
 
-	badWords := []string{
-		"kerfuffle",
-		"sharbert",
-		"fornax",
-	}
-
-	cleaned_body := StripBadWords(chirp.Body, "****", badWords)
-
-	bearerToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		log.Println("error posting chirp: %w", err)
-		sendErrorResponse(w, "error posting chirp")
-		return
-	}
-
-	userID, err := auth.ValidateJWT(bearerToken, cfg.JWT_SECRET)
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
 	if err != nil {
-		sendTokenExpiredResponse(w)
+		sendAuthorizationErrorResponse(w, err)
 		return
 	}
 
@@ -520,12 +685,65 @@ func (cfg *ApiConfig) PostChirpsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	saved_chirp, err := cfg.Db.CreateChirp(r.Context(), database.CreateChirpParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Body:      cleaned_body,
-		UserID:    user.ID,
+	if cfg.REQUIRE_VERIFIED_EMAIL && !user.EmailVerifiedAt.Valid {
+		sendEmailNotVerifiedResponse(w)
+		return
+	}
+
+	// A reply belongs to the thread of the chirp it replies to.
+	parentID := uuid.NullUUID{}
+	rootID := uuid.NullUUID{}
+	if chirp.InReplyTo != nil {
+		parent, err := cfg.rechirpedChirp(r.Context(), *chirp.InReplyTo)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				sendBadRequestResponse(w, "in_reply_to is not a chirp")
+			} else {
+				log.Printf("error getting chirp from database: %v", err)
+				sendErrorResponse(w, "error posting chirp")
+			}
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		rootID = parent.RootChirpID
+		if !rootID.Valid {
+			rootID = parentID
+		}
+	}
+
+	quoteOfID := uuid.NullUUID{}
+	if chirp.QuoteOf != nil {
+		original, err := cfg.rechirpedChirp(r.Context(), *chirp.QuoteOf)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				sendBadRequestResponse(w, "quote_of is not a chirp")
+			} else {
+				log.Printf("error getting chirp from database: %v", err)
+				sendErrorResponse(w, "error posting chirp")
+			}
+			return
+		}
+		quoteOfID = uuid.NullUUID{UUID: original.ID, Valid: true}
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting chirp transaction: %v", err)
+		sendErrorResponse(w, "error posting chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	saved_chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		Body:          cleaned_body,
+		UserID:        user.ID,
+		ParentChirpID: parentID,
+		RootChirpID:   rootID,
+		QuoteOfID:     quoteOfID,
 	})
 
 	if err != nil {
@@ -534,13 +752,32 @@ func (cfg *ApiConfig) PostChirpsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	sendCreatedChirpResponse(w, Chirp{
-		ID:        saved_chirp.ID,
-		CreatedAt: saved_chirp.CreatedAt,
-		UpdatedAt: saved_chirp.UpdatedAt,
-		Body:      saved_chirp.Body,
-		UserID:    saved_chirp.UserID,
-	})
+	err = setChirpHashtags(r.Context(), qtx, saved_chirp)
+	if err != nil {
+		log.Printf("error saving hashtags: %v", err)
+		sendErrorResponse(w, "error posting chirp")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing chirp: %v", err)
+		sendErrorResponse(w, "error posting chirp")
+		return
+	}
+
+	api_chirp, err := cfg.chirpFromDB(r.Context(), user.ID, saved_chirp)
+	if err != nil {
+		log.Printf("error getting chirp author: %v", err)
+		sendErrorResponse(w, "error posting chirp")
+		return
+	}
+	sendCreatedChirpResponse(w, api_chirp)
+}
+
+func (cfg *ApiConfig) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	sendJWKSResponse(w, cfg.JWTKeys.JWKS())
 }
 
 func (cfg *ApiConfig) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
@@ -571,6 +808,11 @@ func (cfg *ApiConfig) MetricsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *ApiConfig) ResetHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.PLATFORM != "dev" {
+		sendResetDisabledResponse(w)
+		return
+	}
+
 	cfg.FileserverHits.Store(0)
 
 	err := cfg.Db.ResetUsers(r.Context())
@@ -590,6 +832,12 @@ func (cfg *ApiConfig) ResetHandler(w http.ResponseWriter, r *http.Request) {
 		log.Println("error resetting database.")
 		sendErrorResponse(w, "error resetting database")
 	}
+
+	err = cfg.Db.ResetLoginFailures(r.Context())
+	if err != nil {
+		log.Println("error resetting database.")
+		sendErrorResponse(w, "error resetting database")
+	}
 }
 
 func (cfg *ApiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -605,3 +853,21 @@ func middlewareLog(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticate returns the id of the user the request's access token was
+// issued to.
+func (cfg *ApiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := cfg.accessToken(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return token.UserID, nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxHashtagLength = 50
+
+	// Trends score each use of a tag in the last trendWindow, halving its
+	// weight every trendHalfLife, so tags rise and fall with how much
+	// they're used right now.
+	trendWindow   = 24 * time.Hour
+	trendHalfLife = 4 * time.Hour
+
+	defaultTrendsLimit = 10
+	maxTrendsLimit     = 50
+)
+
+// hashtagPattern matches a # that starts a word, followed by the tag.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#([\p{L}\p{N}_]+)`)
+
+// extractHashtags returns the distinct hashtags in body, lowercased and
+// without the #. Tags with no letters, such as #1, and tags containing a bad
+// word are left out.
+func extractHashtags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag, ok := normalizeHashtag(match[1])
+		if !ok || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// normalizeHashtag lowercases tag, dropping a leading #, and reports whether
+// it can be a hashtag.
+func normalizeHashtag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if tag == "" || utf8.RuneCountInString(tag) > maxHashtagLength {
+		return "", false
+	}
+	if strings.IndexFunc(tag, unicode.IsLetter) == -1 {
+		return "", false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return "", false
+		}
+	}
+	return tag, !containsBadWord(tag)
+}
+
+// containsBadWord reports whether s has a bad word anywhere in it. Unlike
+// StripBadWords it isn't limited to whole words, since tags are often
+// several words run together.
+func containsBadWord(s string) bool {
+	s = strings.ToLower(s)
+	for _, word := range badWords {
+		if strings.Contains(s, strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// setChirpHashtags replaces the hashtags recorded for chirp with the ones in
+// its body. It should run in the same transaction that saves the body.
+func setChirpHashtags(ctx context.Context, qtx *database.Queries, chirp database.Chirp) error {
+	err := qtx.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range extractHashtags(chirp.Body) {
+		hashtag, err := qtx.UpsertHashtag(ctx, database.UpsertHashtagParams{
+			ID:        uuid.New(),
+			Tag:       tag,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		err = qtx.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID:   chirp.ID,
+			HashtagID: hashtag.ID,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHashtagChirpsHandler lists the chirps using a hashtag a page at a time,
+// newest first.
+func (cfg *ApiConfig) GetHashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	tag, ok := normalizeHashtag(r.PathValue("tag"))
+	if !ok {
+		sendHashtagNotFoundResponse(w)
+		return
+	}
+
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	chirps, err := cfg.Db.ListHashtagChirps(r.Context(), database.ListHashtagChirpsParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting hashtag chirps from database: %v", err)
+		sendErrorResponse(w, "error getting chirps")
+		return
+	}
+
+	cfg.sendChirpPage(w, r, p, chirps, func(i int) string {
+		return chirpCursor{CreatedAt: chirps[i].CreatedAt, ID: chirps[i].ID}.String()
+	})
+}
+
+// GetTrendsHandler lists the hashtags trending right now, highest score
+// first.
+func (cfg *ApiConfig) GetTrendsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTrendsLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			sendInvalidPageResponse(w)
+			return
+		}
+		limit = min(n, maxTrendsLimit)
+	}
+
+	now := time.Now()
+	rows, err := cfg.Db.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Now:             now,
+		HalfLifeSeconds: trendHalfLife.Seconds(),
+		Since:           now.Add(-trendWindow),
+		Limit:           int32(limit),
+	})
+	if err != nil {
+		log.Printf("error getting trends from database: %v", err)
+		sendErrorResponse(w, "error getting trends")
+		return
+	}
+
+	trends := []Trend{}
+	for _, row := range rows {
+		// Tags are checked when they're saved, but the bad word list may
+		// have grown since.
+		if containsBadWord(row.Tag) {
+			continue
+		}
+		trends = append(trends, Trend{
+			Tag:        row.Tag,
+			ChirpCount: row.ChirpCount,
+			Score:      row.Score,
+		})
+	}
+	sendTrendsResponse(w, trends)
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// PostLikeHandler likes a chirp for the user. Liking a chirp twice, or
+// unliking one that isn't liked, changes nothing. Liking a rechirp likes the
+// chirp it reposts.
+func (cfg *ApiConfig) PostLikeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	chirp, err := cfg.rechirpedChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendChirpNotFoundResponse(w)
+		} else {
+			log.Printf("error getting chirp from database: %v", err)
+			sendErrorResponse(w, "error liking chirp")
+		}
+		return
+	}
+
+	err = cfg.Db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:    userID,
+		ChirpID:   chirp.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error liking chirp: %v", err)
+		sendErrorResponse(w, "error liking chirp")
+		return
+	}
+
+	sendLikeUpdatedResponse(w)
+}
+
+func (cfg *ApiConfig) DeleteLikeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	// A chirp deleted since it was liked can still be unliked.
+	chirp, err := cfg.rechirpedChirp(r.Context(), chirpID)
+	if err == nil {
+		chirpID = chirp.ID
+	} else if err != sql.ErrNoRows {
+		log.Printf("error getting chirp from database: %v", err)
+		sendErrorResponse(w, "error unliking chirp")
+		return
+	}
+
+	err = cfg.Db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("error unliking chirp: %v", err)
+		sendErrorResponse(w, "error unliking chirp")
+		return
+	}
+
+	sendLikeUpdatedResponse(w)
+}
+
+// GetChirpLikesHandler lists the users who liked a chirp a page at a time,
+// most recent like first.
+func (cfg *ApiConfig) GetChirpLikesHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	// Likes are paged by (created_at, user_id) with the same cursor chirp
+	// listings use.
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	chirp, err := cfg.rechirpedChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendChirpNotFoundResponse(w)
+		} else {
+			log.Printf("error getting chirp from database: %v", err)
+			sendErrorResponse(w, "error getting likes")
+		}
+		return
+	}
+
+	rows, err := cfg.Db.ListChirpLikers(r.Context(), database.ListChirpLikersParams{
+		ChirpID:         chirp.ID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting likes from database: %v", err)
+		sendErrorResponse(w, "error getting likes")
+		return
+	}
+
+	var nextCursor *string
+	if len(rows) > p.Limit {
+		rows = rows[:p.Limit]
+		last := rows[len(rows)-1]
+		cursor := chirpCursor{CreatedAt: last.LikedAt, ID: last.User.ID}.String()
+		nextCursor = &cursor
+		setNextPageLink(w, r, cursor)
+	}
+
+	likes := make([]ChirpLike, len(rows))
+	for i, row := range rows {
+		likes[i] = ChirpLike{
+			Profile: profileFromDB(row.User),
+			LikedAt: row.LikedAt,
+		}
+	}
+	sendChirpLikesResponse(w, ChirpLikePage{
+		Likes:      likes,
+		NextCursor: nextCursor,
+	})
+}
+
+// GetUserLikesHandler lists the chirps a user liked a page at a time, most
+// recent like first. The user can be given by id or handle.
+func (cfg *ApiConfig) GetUserLikesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.userByIDOrHandle(r.Context(), r.PathValue("user"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error getting likes")
+		}
+		return
+	}
+
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	rows, err := cfg.Db.ListLikedChirps(r.Context(), database.ListLikedChirpsParams{
+		UserID:          user.ID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting liked chirps from database: %v", err)
+		sendErrorResponse(w, "error getting likes")
+		return
+	}
+
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+	cfg.sendChirpPage(w, r, p, chirps, func(i int) string {
+		return chirpCursor{CreatedAt: rows[i].LikedAt, ID: rows[i].Chirp.ID}.String()
+	})
+}
+
+// userByIDOrHandle looks up a user who isn't pending deletion by id or by
+// handle. Handles are shorter than any form of id, so the two never clash.
+func (cfg *ApiConfig) userByIDOrHandle(ctx context.Context, s string) (database.User, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		handle, ok := normalizeHandle(s)
+		if !ok {
+			return database.User{}, sql.ErrNoRows
+		}
+		return cfg.Db.GetUserByHandle(ctx, handle)
+	}
+
+	user, err := cfg.Db.GetUserByID(ctx, id)
+	if err != nil {
+		return database.User{}, err
+	}
+	if user.DeletionRequestedAt.Valid {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Failed logins are counted per account in the database and per client IP
+// in memory. Emails with no account are counted per email in the database
+// the same way accounts are, so lockouts don't reveal which emails are
+// registered. Once either passes its threshold, every further failure doubles
+// how long logins are refused for, up to loginLockoutMax. The IP threshold is
+// higher since many users can share an address.
+const (
+	accountLoginThreshold = 5
+	ipLoginThreshold      = 20
+	loginLockoutBase      = time.Minute
+	loginLockoutMax       = time.Hour
+
+	// ipLoginWindow is how long an IP has to go without a failed login
+	// before its count starts again from zero. accountLoginWindow is the
+	// same for accounts and emails without one, counted from the later of
+	// their last failure and the end of their lockout.
+	ipLoginWindow      = 15 * time.Minute
+	accountLoginWindow = 15 * time.Minute
+)
+
+// loginLockout returns how long logins are refused for after the given
+// number of consecutive failures.
+func loginLockout(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	lockout := loginLockoutBase
+	for i := threshold; i < failures && lockout < loginLockoutMax; i++ {
+		lockout *= 2
+	}
+	return min(lockout, loginLockoutMax)
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginLimiter counts failed logins per client IP. The zero value is ready
+// to use.
+type loginLimiter struct {
+	mu        sync.Mutex
+	attempts  map[string]*loginAttempts
+	lastSweep time.Time
+}
+
+// retryAfter returns how long ip must wait before it may try to log in
+// again, or zero if it may try now.
+func (l *loginLimiter) retryAfter(ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.attempts[ip]
+	if !ok || !now.Before(a.lockedUntil) {
+		return 0
+	}
+	return a.lockedUntil.Sub(now)
+}
+
+func (l *loginLimiter) fail(ip string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.attempts == nil {
+		l.attempts = make(map[string]*loginAttempts)
+	}
+	if now.Sub(l.lastSweep) > ipLoginWindow {
+		for key, a := range l.attempts {
+			if now.Sub(a.lastFailure) > ipLoginWindow && !now.Before(a.lockedUntil) {
+				delete(l.attempts, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	a, ok := l.attempts[ip]
+	if !ok || (now.Sub(a.lastFailure) > ipLoginWindow && !now.Before(a.lockedUntil)) {
+		a = &loginAttempts{}
+		l.attempts[ip] = a
+	}
+	a.failures++
+	a.lastFailure = now
+	if lockout := loginLockout(a.failures, ipLoginThreshold); lockout > 0 {
+		a.lockedUntil = now.Add(lockout)
+	}
+}
+
+// accountRetryAfter returns how long user must wait before they may try to
+// log in again, or zero if they may try now.
+func accountRetryAfter(user database.User, now time.Time) time.Duration {
+	if !user.LockedUntil.Valid || !now.Before(user.LockedUntil.Time) {
+		return 0
+	}
+	return user.LockedUntil.Time.Sub(now)
+}
+
+// recordFailedLogin counts a failed password or second factor against both
+// the client IP and user, locking the account once it has failed too often.
+func (cfg *ApiConfig) recordFailedLogin(ctx context.Context, ip string, user database.User) error {
+	now := time.Now()
+	cfg.loginLimiter.fail(ip, now)
+
+	cfg.pruneLoginFailures(ctx, now)
+
+	failures, err := cfg.Db.RecordFailedLogin(ctx, database.RecordFailedLoginParams{
+		WindowStart: now.Add(-accountLoginWindow),
+		FailedAt:    now,
+		ID:          user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	lockout := loginLockout(int(failures), accountLoginThreshold)
+	if lockout == 0 {
+		return nil
+	}
+	log.Printf("locking user %v for %v after %d failed logins", user.ID, lockout, failures)
+	return cfg.Db.LockUser(ctx, database.LockUserParams{
+		ID:          user.ID,
+		LockedUntil: sql.NullTime{Time: now.Add(lockout), Valid: true},
+	})
+}
+
+// unknownEmailRetryAfter is accountRetryAfter for an email with no account.
+func (cfg *ApiConfig) unknownEmailRetryAfter(ctx context.Context, email string, now time.Time) (time.Duration, error) {
+	failure, err := cfg.Db.GetLoginFailure(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !failure.LockedUntil.Valid || !now.Before(failure.LockedUntil.Time) {
+		return 0, nil
+	}
+	return failure.LockedUntil.Time.Sub(now), nil
+}
+
+// recordUnknownEmailFailure is recordFailedLogin for an email with no
+// account.
+func (cfg *ApiConfig) recordUnknownEmailFailure(ctx context.Context, ip string, email string) error {
+	now := time.Now()
+	cfg.loginLimiter.fail(ip, now)
+
+	cfg.pruneLoginFailures(ctx, now)
+
+	failures, err := cfg.Db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Email:       email,
+		FailedAt:    now,
+		WindowStart: now.Add(-accountLoginWindow),
+	})
+	if err != nil {
+		return err
+	}
+
+	lockout := loginLockout(int(failures), accountLoginThreshold)
+	if lockout == 0 {
+		return nil
+	}
+	return cfg.Db.LockLoginFailure(ctx, database.LockLoginFailureParams{
+		Email:       email,
+		LockedUntil: sql.NullTime{Time: now.Add(lockout), Valid: true},
+	})
+}
+
+// pruneLoginFailures forgets emails without an account whose count would
+// start again anyway. It runs for every failed login, not only ones for
+// unknown emails, so both take the same work.
+func (cfg *ApiConfig) pruneLoginFailures(ctx context.Context, now time.Time) {
+	err := cfg.Db.DeleteExpiredLoginFailures(ctx, now.Add(-accountLoginWindow))
+	if err != nil {
+		log.Printf("error deleting expired login failures: %v", err)
+	}
+}
+
+// recordSuccessfulLogin clears the user's failed login count.
+func (cfg *ApiConfig) recordSuccessfulLogin(ctx context.Context, user database.User) error {
+	if user.FailedLoginCount == 0 && !user.LockedUntil.Valid {
+		return nil
+	}
+	_, err := cfg.Db.UnlockUser(ctx, user.ID)
+	return err
+}
+
+func (cfg *ApiConfig) PostUnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	unlocked, err := cfg.Db.UnlockUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("error unlocking user: %v", err)
+		sendErrorResponse(w, "error unlocking user")
+		return
+	}
+	if unlocked == 0 {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	sendUserUnlockedResponse(w)
+}
@@ -16,7 +16,8 @@ type PolkaData struct {
 }
 
 type AccessToken struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type LoginParams struct {
@@ -25,21 +26,218 @@ type LoginParams struct {
 	ExpiresInSeconds int    `json:"expires_in_seconds"`
 }
 
+type UserRoleParams struct {
+	Role string `json:"role"`
+}
+
+type UserParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Handle   string `json:"handle"`
+}
+
+type UserPutParams struct {
+	Email           string `json:"email"`
+	Password        string `json:"password"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// UserPatchParams holds the fields to change. Fields left out of the
+// request are nil and keep their current value.
+type UserPatchParams struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+	Handle          *string `json:"handle"`
+	DisplayName     *string `json:"display_name"`
+	Bio             *string `json:"bio"`
+}
+
+type AccountDeletionParams struct {
+	Password string `json:"password"`
+}
+
+type AccountDeletion struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+type PasswordResetParams struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmParams struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// User is the private view of an account, only ever sent to the account's
+// owner. Token and RefreshToken are only set in login responses.
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Password     string    `json:"password"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Handle        string    `json:"handle"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	Token         string    `json:"token,omitempty"`
+	RefreshToken  string    `json:"refresh_token,omitempty"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	EmailVerified bool      `json:"email_verified"`
+}
+
+// Profile is the public view of a user. It must never carry anything more
+// than what the user chose to show.
+type Profile struct {
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
+// Chirp is a chirp as shown to anyone. A deleted chirp that still has
+// replies or quotes is kept as a tombstone: Deleted is set and the body and
+// author are left out. A rechirp has no body of its own and embeds the chirp
+// it reposts in RechirpOf; a quote embeds the chirp it quotes in QuoteOf.
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Body         string     `json:"body"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Author       *Profile   `json:"author"`
+	InReplyTo    *uuid.UUID `json:"in_reply_to"`
+	RootChirpID  *uuid.UUID `json:"root_chirp_id"`
+	RechirpOf    *Chirp     `json:"rechirp_of,omitempty"`
+	QuoteOf      *Chirp     `json:"quote_of,omitempty"`
+	LikeCount    int64      `json:"like_count"`
+	LikedByMe    bool       `json:"liked_by_me"`
+	RechirpCount int64      `json:"rechirp_count"`
+	QuoteCount   int64      `json:"quote_count"`
+	Deleted      bool       `json:"deleted,omitempty"`
+}
+
+// ChirpParams is the body of a request to post a chirp.
+type ChirpParams struct {
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
+}
+
+type ChirpEditParams struct {
+	Body string `json:"body"`
+}
+
+// ChirpRevision is a body a chirp had before it was edited. CreatedAt is when
+// the body was written and ReplacedAt when an edit replaced it.
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// ChirpPage is one page of a chirp listing. NextCursor is null on the last
+// page.
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// ChirpThread is a chirp with the chain of chirps it replies to, root
+// first, and a page of the replies to it.
+type ChirpThread struct {
+	Ancestors     []Chirp       `json:"ancestors"`
+	MoreAncestors bool          `json:"more_ancestors"`
+	Chirp         Chirp         `json:"chirp"`
+	Replies       []ThreadReply `json:"replies"`
+	NextCursor    *string       `json:"next_cursor"`
+}
+
+// ThreadReply is a reply with its own replies, down to the depth asked for.
+// MoreReplies is set when it has replies that were left out.
+type ThreadReply struct {
+	Chirp
+	Replies     []ThreadReply `json:"replies"`
+	MoreReplies bool          `json:"more_replies"`
+}
+
+// ChirpLike is a user who liked a chirp.
+type ChirpLike struct {
+	Profile
+	LikedAt time.Time `json:"liked_at"`
+}
+
+type ChirpLikePage struct {
+	Likes      []ChirpLike `json:"likes"`
+	NextCursor *string     `json:"next_cursor"`
+}
+
+// Follow is a user in a follower or following listing.
+type Follow struct {
+	Profile
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor *string  `json:"next_cursor"`
+}
+
+// Trend is a hashtag trending right now. Score weighs recent uses of the tag
+// more than older ones.
+type Trend struct {
+	Tag        string  `json:"tag"`
+	ChirpCount int64   `json:"chirp_count"`
+	Score      float64 `json:"score"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeParams struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorLoginParams struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type PersonalAccessTokenParams struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Token      string     `json:"token,omitempty"`
 }
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/crisp-coder/chirpy/internal/oidc"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcLoginStateDuration = 10 * time.Minute
+
+	// oidcStateCookie holds the state of a login in progress so the
+	// callback can check it is completing a login started by the same
+	// browser.
+	oidcStateCookie = "chirpy_oidc_state"
+)
+
+var errEmailNotVerified = errors.New("identity provider has not verified the email address")
+
+func (cfg *ApiConfig) GetOIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.OIDC == nil {
+		sendOIDCDisabledResponse(w)
+		return
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		log.Printf("error generating oidc state: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		log.Printf("error generating oidc nonce: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		log.Printf("error generating pkce verifier: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+
+	err = cfg.Db.DeleteExpiredOIDCLoginStates(r.Context(), time.Now())
+	if err != nil {
+		log.Printf("error deleting expired oidc login states: %v", err)
+	}
+
+	err = cfg.Db.CreateOIDCLoginState(r.Context(), database.CreateOIDCLoginStateParams{
+		StateHash:    auth.HashToken(state, cfg.TOKEN_HASH_KEY),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().Add(oidcLoginStateDuration),
+	})
+	if err != nil {
+		log.Printf("error saving oidc login state: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+
+	authURL, err := cfg.OIDC.AuthCodeURL(r.Context(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		log.Printf("error building oidc authorization url: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   int(oidcLoginStateDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.APP_BASE_URL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (cfg *ApiConfig) GetOIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.OIDC == nil {
+		sendOIDCDisabledResponse(w)
+		return
+	}
+
+	query := r.URL.Query()
+	if idpErr := query.Get("error"); idpErr != "" {
+		log.Printf("identity provider returned error: %s %s", idpErr, query.Get("error_description"))
+		sendOIDCLoginFailedResponse(w)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		sendOIDCLoginFailedResponse(w)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/oidc", MaxAge: -1})
+
+	loginState, err := cfg.Db.UseOIDCLoginState(r.Context(), database.UseOIDCLoginStateParams{
+		StateHash: auth.HashToken(state, cfg.TOKEN_HASH_KEY),
+		ExpiresAt: time.Now(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendOIDCLoginFailedResponse(w)
+		} else {
+			log.Printf("error getting oidc login state: %v", err)
+			sendErrorResponse(w, "error logging in")
+		}
+		return
+	}
+
+	claims, err := cfg.OIDC.Exchange(r.Context(), query.Get("code"), loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		log.Printf("error completing oidc login: %v", err)
+		sendOIDCLoginFailedResponse(w)
+		return
+	}
+
+	user, err := cfg.userForIdentity(r.Context(), claims)
+	if err != nil {
+		if err == errEmailNotVerified {
+			sendOIDCEmailNotVerifiedResponse(w)
+		} else {
+			log.Printf("error finding user for oidc identity: %v", err)
+			sendErrorResponse(w, "error logging in")
+		}
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		cfg.sendTwoFactorChallenge(w, user)
+		return
+	}
+
+	cfg.finishLogin(w, r, user)
+}
+
+// userForIdentity returns the user linked to the identity in claims. An
+// identity seen for the first time is linked to the user with the same
+// email address, or to a new user if there is none. Both rely on the
+// provider having verified the email address.
+func (cfg *ApiConfig) userForIdentity(ctx context.Context, claims *oidc.Claims) (database.User, error) {
+	identity, err := cfg.Db.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+	})
+	if err == nil {
+		return cfg.Db.GetUserByID(ctx, identity.UserID)
+	}
+	if err != sql.ErrNoRows {
+		return database.User{}, err
+	}
+
+	if !claims.EmailVerified || !validEmail(claims.Email) {
+		return database.User{}, errEmailNotVerified
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	user, err := qtx.GetUserByEmail(ctx, claims.Email)
+	if err == sql.ErrNoRows {
+		user, err = cfg.createOIDCUser(ctx, qtx, claims.Email)
+	} else if err == nil && !user.EmailVerifiedAt.Valid {
+		// Anyone could have signed up with this address before its owner
+		// arrived through the provider. Lock them out of the account.
+		err = cfg.resetUnverifiedAccount(ctx, qtx, &user)
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+
+	// The provider has verified the address, so there is no need to
+	// send our own verification email.
+	if !user.EmailVerifiedAt.Valid {
+		now := time.Now()
+		_, err = qtx.VerifyUserEmail(ctx, database.VerifyUserEmailParams{
+			ID:              user.ID,
+			UpdatedAt:       now,
+			EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+			Email:           user.Email,
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+		user.UpdatedAt = now
+		user.EmailVerifiedAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	err = qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// resetUnverifiedAccount removes every credential from a user whose email
+// was never verified: their password, sessions, personal access tokens and
+// second factor.
+func (cfg *ApiConfig) resetUnverifiedAccount(ctx context.Context, db *database.Queries, user *database.User) error {
+	hashedPassword, err := cfg.unusablePasswordHash()
+	if err != nil {
+		return err
+	}
+
+	err = db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:             user.ID,
+		UpdatedAt:      time.Now(),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = db.RevokeRefreshTokensByUserID(ctx, database.RevokeRefreshTokensByUserIDParams{
+		UserID:    user.ID,
+		UpdatedAt: time.Now(),
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = db.RevokePersonalAccessTokensByUserID(ctx, database.RevokePersonalAccessTokensByUserIDParams{
+		UserID:    user.ID,
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = db.DisableUserTOTP(ctx, database.DisableUserTOTPParams{
+		ID:        user.ID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	user.TotpSecret = sql.NullString{}
+	user.TotpEnabledAt = sql.NullTime{}
+
+	return db.DeleteRecoveryCodesByUserID(ctx, user.ID)
+}
+
+// unusablePasswordHash returns the hash of a random password nobody knows,
+// for users who sign in through the identity provider. They can set a real
+// password through the password reset flow.
+func (cfg *ApiConfig) unusablePasswordHash() (string, error) {
+	password, err := auth.MakeSecureToken()
+	if err != nil {
+		return "", err
+	}
+	return cfg.Passwords.Hash(password)
+}
+
+// createOIDCUser creates a user who signs in through the identity provider.
+func (cfg *ApiConfig) createOIDCUser(ctx context.Context, db *database.Queries, email string) (database.User, error) {
+	hashedPassword, err := cfg.unusablePasswordHash()
+	if err != nil {
+		return database.User{}, err
+	}
+	handle, err := generateHandle()
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return db.CreateUser(ctx, database.CreateUserParams{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Handle:         handle,
+	})
+}
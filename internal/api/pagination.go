@@ -0,0 +1,177 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+var errInvalidPage = errors.New("invalid limit or cursor")
+
+// Cursors mark the last chirp of a page so the next page can start just
+// past it. Clients get them base64 encoded and should treat them as opaque.
+
+// chirpCursor is the cursor of listings ordered by (created_at, id).
+type chirpCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c chirpCursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseChirpCursor(s string) (chirpCursor, error) {
+	key, id, err := decodeCursor(s)
+	if err != nil {
+		return chirpCursor{}, err
+	}
+	us, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return chirpCursor{}, errInvalidPage
+	}
+	return chirpCursor{CreatedAt: time.UnixMicro(us).UTC(), ID: id}, nil
+}
+
+// searchCursor is the cursor of search results, ordered by (rank, id).
+type searchCursor struct {
+	Rank float32
+	ID   uuid.UUID
+}
+
+func (c searchCursor) String() string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseSearchCursor(s string) (searchCursor, error) {
+	key, id, err := decodeCursor(s)
+	if err != nil {
+		return searchCursor{}, err
+	}
+	rank, err := strconv.ParseFloat(key, 32)
+	if err != nil {
+		return searchCursor{}, errInvalidPage
+	}
+	return searchCursor{Rank: float32(rank), ID: id}, nil
+}
+
+// decodeCursor splits a cursor into its sort key and chirp id.
+func decodeCursor(s string) (string, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", uuid.Nil, errInvalidPage
+	}
+	key, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return "", uuid.Nil, errInvalidPage
+	}
+	cursorID, err := uuid.Parse(id)
+	if err != nil {
+		return "", uuid.Nil, errInvalidPage
+	}
+	return key, cursorID, nil
+}
+
+// page is a request for one page of a listing. An empty cursor asks for the
+// first page; otherwise it is parsed by the listing it belongs to.
+type page struct {
+	Limit  int
+	Cursor string
+}
+
+func parsePage(query url.Values) (page, error) {
+	p := page{Limit: defaultPageLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return page{}, errInvalidPage
+		}
+		p.Limit = min(n, maxPageLimit)
+	}
+
+	p.Cursor = query.Get("cursor")
+	return p, nil
+}
+
+// fetchLimit is how many rows to ask the database for: one more than the
+// page holds, to tell whether there is a next page.
+func (p page) fetchLimit() int32 {
+	return int32(p.Limit + 1)
+}
+
+// chirpCursorArgs parses the cursor of a listing ordered by (created_at, id)
+// into the cursor parameters of its query. Both are zero on the first page.
+func (p page) chirpCursorArgs() (sql.NullTime, uuid.UUID, error) {
+	if p.Cursor == "" {
+		return sql.NullTime{}, uuid.Nil, nil
+	}
+	cursor, err := parseChirpCursor(p.Cursor)
+	if err != nil {
+		return sql.NullTime{}, uuid.Nil, err
+	}
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, cursor.ID, nil
+}
+
+// searchCursorArgs is chirpCursorArgs for search results.
+func (p page) searchCursorArgs() (sql.NullFloat64, uuid.UUID, error) {
+	if p.Cursor == "" {
+		return sql.NullFloat64{}, uuid.Nil, nil
+	}
+	cursor, err := parseSearchCursor(p.Cursor)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.Nil, err
+	}
+	return sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}, cursor.ID, nil
+}
+
+// setNextPageLink points the Link header at the page after the current one,
+// keeping every other query parameter of the request.
+func setNextPageLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", "<"+next.String()+">; rel=\"next\"")
+}
+
+// sendChirpPage responds with chirps, fetched with p.fetchLimit, as one page
+// of a listing. cursorAt returns the cursor pointing just past chirps[i].
+func (cfg *ApiConfig) sendChirpPage(w http.ResponseWriter, r *http.Request, p page, chirps []database.Chirp, cursorAt func(i int) string) {
+	var nextCursor *string
+	if len(chirps) > p.Limit {
+		chirps = chirps[:p.Limit]
+		cursor := cursorAt(len(chirps) - 1)
+		nextCursor = &cursor
+	}
+
+	api_chirps, err := cfg.chirpsFromDB(r.Context(), cfg.viewer(r), chirps)
+	if err != nil {
+		log.Printf("error getting chirp authors: %v", err)
+		sendErrorResponse(w, "error getting chirps")
+		return
+	}
+
+	if nextCursor != nil {
+		setNextPageLink(w, r, *nextCursor)
+	}
+	sendChirpsResponse(w, ChirpPage{
+		Chirps:     api_chirps,
+		NextCursor: nextCursor,
+	})
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"log"
+	"net/http"
+)
+
+// acceptablePassword checks password against the password policy for the
+// account with the given email. If it is rejected, or can't be checked, the
+// response has already been sent.
+func (cfg *ApiConfig) acceptablePassword(w http.ResponseWriter, password, email string) bool {
+	failed, err := cfg.PasswordPolicy.Check(password, email)
+	if err != nil {
+		log.Printf("error checking password policy: %v", err)
+		sendErrorResponse(w, "error checking password")
+		return false
+	}
+	if len(failed) > 0 {
+		sendPasswordRejectedResponse(w, failed)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/crisp-coder/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const passwordResetTokenDuration = time.Hour
+
+// PostPasswordResetHandler emails a single-use reset link to the address in
+// the request. It answers the same way whether or not the address belongs
+// to an account so it cannot be used to discover users.
+func (cfg *ApiConfig) PostPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	params := PasswordResetParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding password reset params: %v", err)
+		sendErrorResponse(w, "error requesting password reset")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByEmail(r.Context(), params.Email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error requesting password reset")
+			return
+		}
+		sendPasswordResetRequestedResponse(w)
+		return
+	}
+
+	token, err := auth.MakeSecureToken()
+	if err != nil {
+		log.Printf("error creating password reset token: %v", err)
+		sendErrorResponse(w, "error requesting password reset")
+		return
+	}
+
+	_, err = cfg.Db.CreatePasswordResetToken(r.Context(), database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token, cfg.TOKEN_HASH_KEY),
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(passwordResetTokenDuration),
+	})
+	if err != nil {
+		log.Printf("error inserting password reset token into database: %v", err)
+		sendErrorResponse(w, "error requesting password reset")
+		return
+	}
+
+	// The email is sent in the background so the response takes as long as
+	// it does for an address without an account.
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Use this link within the next hour to choose a new one:\n%s/reset-password?token=%s\n\n"+
+			"If this wasn't you, you can ignore this email.\n",
+			cfg.APP_BASE_URL, url.QueryEscape(token)),
+	}
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		err := cfg.Mailer.Send(ctx, msg)
+		if err != nil {
+			log.Printf("error sending password reset email: %v", err)
+		}
+	}()
+
+	sendPasswordResetRequestedResponse(w)
+}
+
+// PostPasswordResetConfirmHandler sets a new password using a reset token.
+// The token is consumed and every session the user had is revoked.
+func (cfg *ApiConfig) PostPasswordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	params := PasswordResetConfirmParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding password reset params: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting password reset transaction: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	resetToken, err := qtx.UsePasswordResetToken(r.Context(), database.UsePasswordResetTokenParams{
+		TokenHash: auth.HashToken(params.Token, cfg.TOKEN_HASH_KEY),
+		UsedAt:    sql.NullTime{Time: time.Now(), Valid: true},
+		ExpiresAt: time.Now(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendInvalidResetTokenResponse(w)
+		} else {
+			log.Printf("error using password reset token: %v", err)
+			sendErrorResponse(w, "error resetting password")
+		}
+		return
+	}
+
+	user, err := qtx.GetUserByID(r.Context(), resetToken.UserID)
+	if err != nil {
+		log.Printf("error getting user from database: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	// The transaction is rolled back on a rejected password, so the token
+	// can be used again with a better one.
+	if !cfg.acceptablePassword(w, params.Password, user.Email) {
+		return
+	}
+
+	hashedPassword, err := cfg.Passwords.Hash(params.Password)
+	if err != nil {
+		log.Printf("error hashing password: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             resetToken.UserID,
+		UpdatedAt:      time.Now(),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("error updating password: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	err = qtx.DeletePasswordResetTokensByUserID(r.Context(), resetToken.UserID)
+	if err != nil {
+		log.Printf("error deleting password reset tokens: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	err = qtx.RevokeRefreshTokensByUserID(r.Context(), database.RevokeRefreshTokensByUserIDParams{
+		UserID:    resetToken.UserID,
+		UpdatedAt: time.Now(),
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking sessions: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing password reset: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	err = cfg.revokeUserAccessTokens(r.Context(), resetToken.UserID, uuid.Nil)
+	if err != nil {
+		log.Printf("error revoking access tokens: %v", err)
+		sendErrorResponse(w, "error resetting password")
+		return
+	}
+
+	sendPasswordResetResponse(w)
+}
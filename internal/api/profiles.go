@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// reservedHandles would clash with other routes under /api/users.
+var reservedHandles = map[string]bool{
+	"me":     true,
+	"verify": true,
+}
+
+// normalizeHandle lowercases handle and reports whether it is allowed.
+// Handles are unique regardless of case.
+func normalizeHandle(handle string) (string, bool) {
+	handle = strings.ToLower(strings.TrimSpace(handle))
+	return handle, handlePattern.MatchString(handle) && !reservedHandles[handle]
+}
+
+// generateHandle makes a handle for users who didn't choose one. They can
+// change it later.
+func generateHandle() (string, error) {
+	b := make([]byte, 6)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "user_" + hex.EncodeToString(b), nil
+}
+
+func validProfileText(displayName, bio string) bool {
+	return utf8.RuneCountInString(displayName) <= maxDisplayNameLength &&
+		utf8.RuneCountInString(bio) <= maxBioLength
+}
+
+// profileFromDB returns the fields of user anyone can see.
+func profileFromDB(user database.User) Profile {
+	return Profile{
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// userFromDB returns the fields of user only they can see, without any
+// tokens.
+func userFromDB(user database.User) User {
+	return User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Handle:        user.Handle,
+		DisplayName:   user.DisplayName,
+		Bio:           user.Bio,
+		IsChirpyRed:   user.IsChirpyRed.Bool,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+	}
+}
+
+// authorProfiles loads the profiles of the authors of chirps, keyed by
+// user id. Authors pending deletion are left out.
+func (cfg *ApiConfig) authorProfiles(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]Profile, error) {
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, chirp := range chirps {
+		if !seen[chirp.UserID] {
+			seen[chirp.UserID] = true
+			ids = append(ids, chirp.UserID)
+		}
+	}
+
+	users, err := cfg.Db.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[uuid.UUID]Profile, len(users))
+	for _, user := range users {
+		profiles[user.ID] = profileFromDB(user)
+	}
+	return profiles, nil
+}
+
+func (cfg *ApiConfig) GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	handle, ok := normalizeHandle(r.PathValue("handle"))
+	if !ok {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	user, err := cfg.Db.GetUserByHandle(r.Context(), handle)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error getting user")
+		}
+		return
+	}
+
+	sendProfileResponse(w, profileFromDB(user))
+}
+
+// GetMeHandler returns the private view of the user's own account.
+func (cfg *ApiConfig) GetMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeProfileRead)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendTokenExpiredResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error getting user")
+		}
+		return
+	}
+
+	sendUserResponse(w, userFromDB(user))
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// rechirpedChirp returns the chirp that rechirping, quoting or replying to
+// chirpID acts on. For a rechirp that is the chirp it reposts, so they always
+// point at the original.
+func (cfg *ApiConfig) rechirpedChirp(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.Db.GetChirp(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chirp.RechirpOfID.Valid {
+		return cfg.Db.GetChirp(ctx, chirp.RechirpOfID.UUID)
+	}
+	return chirp, nil
+}
+
+// PostRechirpHandler reposts a chirp as it is. A user can rechirp each chirp
+// once.
+func (cfg *ApiConfig) PostRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error rechirping")
+		}
+		return
+	}
+
+	if cfg.REQUIRE_VERIFIED_EMAIL && !user.EmailVerifiedAt.Valid {
+		sendEmailNotVerifiedResponse(w)
+		return
+	}
+
+	original, err := cfg.rechirpedChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendChirpNotFoundResponse(w)
+		} else {
+			log.Printf("error getting chirp from database: %v", err)
+			sendErrorResponse(w, "error rechirping")
+		}
+		return
+	}
+
+	rechirp, err := cfg.Db.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      user.ID,
+		RechirpOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if err != nil {
+		if isUniqueViolation(err, "chirps_rechirp_key") {
+			sendAlreadyRechirpedResponse(w)
+		} else {
+			log.Printf("error creating rechirp: %v", err)
+			sendErrorResponse(w, "error rechirping")
+		}
+		return
+	}
+
+	api_chirp, err := cfg.chirpFromDB(r.Context(), user.ID, rechirp)
+	if err != nil {
+		log.Printf("error getting chirp author: %v", err)
+		sendErrorResponse(w, "error rechirping")
+		return
+	}
+	sendCreatedChirpResponse(w, api_chirp)
+}
+
+// DeleteRechirpHandler undoes the user's rechirp of a chirp.
+func (cfg *ApiConfig) DeleteRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeChirpsWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	deleted, err := cfg.Db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		RechirpOfID: uuid.NullUUID{UUID: chirpID, Valid: true},
+		UserID:      userID,
+	})
+	if err != nil {
+		log.Printf("error deleting rechirp: %v", err)
+		sendErrorResponse(w, "error deleting rechirp")
+		return
+	}
+	if deleted == 0 {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	sendChirpDeletedResponse(w)
+}
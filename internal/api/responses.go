@@ -4,13 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
 )
 
 type ErrResp struct {
 	Error string `json:"error"`
 }
 
+type PasswordRejectedResp struct {
+	Error       string   `json:"error"`
+	FailedRules []string `json:"failed_rules"`
+}
+
 type ValidResp struct {
 	Valid       bool   `json:"valid"`
 	CleanedBody string `json:"cleaned_body"`
@@ -87,8 +97,45 @@ func sendUserNotFoundResponse(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNotFound)
 }
 
-func sendIncorrectPasswordResponse(w http.ResponseWriter) {
-	w.WriteHeader(http.StatusUnauthorized)
+func sendInvalidCredentialsResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusUnauthorized, ErrResp{
+		Error: "Incorrect email or password",
+	})
+}
+
+func sendTooManyLoginAttemptsResponse(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	sendJSONResponse(w, http.StatusTooManyRequests, ErrResp{
+		Error: "Too many failed login attempts, try again later",
+	})
+}
+
+func sendUserUnlockedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendUserRoleUpdatedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendAccountDeletionScheduledResponse(w http.ResponseWriter, deletion AccountDeletion) {
+	sendJSONResponse(w, http.StatusAccepted, deletion)
+}
+
+func sendUserSuspendedResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusForbidden, ErrResp{
+		Error: "Account is suspended",
+	})
+}
+
+func sendUserSuspensionUpdatedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendResetDisabledResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusForbidden, ErrResp{
+		Error: "Reset is only allowed on the dev platform",
+	})
 }
 
 func sendChirpNotFoundResponse(w http.ResponseWriter) {
@@ -110,6 +157,22 @@ func sendAccessTokenResponse(w http.ResponseWriter, token AccessToken) {
 	}
 }
 
+func sendJWKSResponse(w http.ResponseWriter, jwks auth.JWKS) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	dat, err := json.Marshal(jwks)
+	if err != nil {
+		log.Printf("error marshalling JSON: %s", err)
+		return
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		log.Println("error writing response: %w", err)
+		return
+	}
+}
+
 func sendTokenExpiredResponse(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusUnauthorized)
 }
@@ -133,7 +196,7 @@ func sendChirpResponse(w http.ResponseWriter, chirp Chirp) {
 	}
 }
 
-func sendChirpsResponse(w http.ResponseWriter, chirps []Chirp) {
+func sendChirpsResponse(w http.ResponseWriter, chirps ChirpPage) {
 	w.WriteHeader(http.StatusOK)
 	dat, err := json.Marshal(chirps)
 	if err != nil {
@@ -148,6 +211,58 @@ func sendChirpsResponse(w http.ResponseWriter, chirps []Chirp) {
 	}
 }
 
+func sendChirpEditWindowClosedResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusForbidden, ErrResp{
+		Error: "Chirp can no longer be edited",
+	})
+}
+
+func sendChirpRevisionsResponse(w http.ResponseWriter, revisions []ChirpRevision) {
+	sendJSONResponse(w, http.StatusOK, revisions)
+}
+
+func sendChirpThreadResponse(w http.ResponseWriter, thread ChirpThread) {
+	sendJSONResponse(w, http.StatusOK, thread)
+}
+
+func sendInvalidThreadDepthResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, fmt.Sprintf("depth must be between 1 and %d", maxThreadDepth))
+}
+
+func sendAlreadyRechirpedResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusConflict, ErrResp{
+		Error: "Chirp is already rechirped",
+	})
+}
+
+func sendLikeUpdatedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendChirpLikesResponse(w http.ResponseWriter, likes ChirpLikePage) {
+	sendJSONResponse(w, http.StatusOK, likes)
+}
+
+func sendFollowUpdatedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendFollowsResponse(w http.ResponseWriter, follows FollowPage) {
+	sendJSONResponse(w, http.StatusOK, follows)
+}
+
+func sendHashtagNotFoundResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func sendTrendsResponse(w http.ResponseWriter, trends []Trend) {
+	sendJSONResponse(w, http.StatusOK, trends)
+}
+
+func sendInvalidPageResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, "limit must be a positive number and cursor must come from next_cursor")
+}
+
 func sendCreatedChirpResponse(w http.ResponseWriter, chirp Chirp) {
 	w.WriteHeader(http.StatusCreated)
 	dat, err := json.Marshal(chirp)
@@ -163,6 +278,217 @@ func sendCreatedChirpResponse(w http.ResponseWriter, chirp Chirp) {
 	}
 }
 
+func sendSessionsResponse(w http.ResponseWriter, sessions []Session) {
+	w.WriteHeader(http.StatusOK)
+	dat, err := json.Marshal(sessions)
+	if err != nil {
+		log.Printf("error marshalling JSON: %s", err)
+		return
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		log.Println("error writing response: %w", err)
+		return
+	}
+}
+
+func sendSessionRevokedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendSessionNotFoundResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func sendPasswordResetRequestedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func sendPasswordResetResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendInvalidResetTokenResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, "Reset token is invalid or has expired")
+}
+
+func sendEmailVerifiedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendVerificationEmailSentResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func sendInvalidVerificationTokenResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, "Verification token is invalid or has expired")
+}
+
+func sendInvalidEmailResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, "Email address is invalid")
+}
+
+func sendPasswordRejectedResponse(w http.ResponseWriter, failedRules []string) {
+	sendJSONResponse(w, http.StatusBadRequest, PasswordRejectedResp{
+		Error:       "Password does not meet the password policy",
+		FailedRules: failedRules,
+	})
+}
+
+func sendEmailInUseResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusConflict, ErrResp{
+		Error: "Email address is already in use",
+	})
+}
+
+func sendInvalidHandleResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, "Handle must be 3 to 30 letters, digits or underscores")
+}
+
+func sendHandleInUseResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusConflict, ErrResp{
+		Error: "Handle is already in use",
+	})
+}
+
+func sendProfileTooLongResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, fmt.Sprintf("Display name must be at most %d characters and bio at most %d", maxDisplayNameLength, maxBioLength))
+}
+
+func sendProfileResponse(w http.ResponseWriter, profile Profile) {
+	sendJSONResponse(w, http.StatusOK, profile)
+}
+
+func sendUserResponse(w http.ResponseWriter, user User) {
+	sendJSONResponse(w, http.StatusOK, user)
+}
+
+func sendEmailNotVerifiedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	respBody := ErrResp{
+		Error: "Email address must be verified before posting chirps",
+	}
+
+	dat, err := json.Marshal(respBody)
+	if err != nil {
+		log.Printf("error marshalling JSON: %s", err)
+		return
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		log.Println("error writing response: %w", err)
+		return
+	}
+}
+
+func sendBadRequestResponse(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusBadRequest)
+	respBody := ErrResp{
+		Error: message,
+	}
+
+	dat, err := json.Marshal(respBody)
+	if err != nil {
+		log.Printf("error marshalling JSON: %s", err)
+		return
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		log.Println("error writing response: %w", err)
+		return
+	}
+}
+
+// sendJSONResponse writes payload as the JSON body of a response with the
+// given status code.
+func sendJSONResponse(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error marshalling JSON: %s", err)
+		return
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
+func sendTwoFactorChallengeResponse(w http.ResponseWriter, challenge TwoFactorChallenge) {
+	sendJSONResponse(w, http.StatusOK, challenge)
+}
+
+func sendTwoFactorEnrollmentResponse(w http.ResponseWriter, enrollment TwoFactorEnrollment) {
+	sendJSONResponse(w, http.StatusOK, enrollment)
+}
+
+func sendRecoveryCodesResponse(w http.ResponseWriter, codes RecoveryCodes) {
+	sendJSONResponse(w, http.StatusOK, codes)
+}
+
+func sendTwoFactorDisabledResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendTwoFactorAlreadyEnabledResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusConflict, ErrResp{
+		Error: "Two-factor authentication is already enabled",
+	})
+}
+
+func sendTwoFactorNotEnabledResponse(w http.ResponseWriter) {
+	sendBadRequestResponse(w, "Two-factor authentication is not enabled")
+}
+
+func sendInvalidTwoFactorCodeResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusUnauthorized, ErrResp{
+		Error: "Invalid two-factor code",
+	})
+}
+
+func sendInsufficientScopeResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusForbidden, ErrResp{
+		Error: "Token does not have the required scope",
+	})
+}
+
+func sendCreatedAccessTokenResponse(w http.ResponseWriter, token PersonalAccessToken) {
+	sendJSONResponse(w, http.StatusCreated, token)
+}
+
+func sendAccessTokensResponse(w http.ResponseWriter, tokens []PersonalAccessToken) {
+	sendJSONResponse(w, http.StatusOK, tokens)
+}
+
+func sendAccessTokenRevokedResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sendAccessTokenNotFoundResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func sendOIDCDisabledResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func sendOIDCLoginFailedResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusUnauthorized, ErrResp{
+		Error: "Single sign-on login failed",
+	})
+}
+
+func sendOIDCEmailNotVerifiedResponse(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusForbidden, ErrResp{
+		Error: "Identity provider has not verified your email address",
+	})
+}
+
 func sendCleanedResponse(w http.ResponseWriter, cleaned_body string) {
 	w.WriteHeader(http.StatusOK)
 	respBody := ValidResp{
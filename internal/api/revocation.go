@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Access tokens can't be taken back once handed out, so every refresh token
+// records the id of the access token issued alongside it. Revoking a session
+// adds the ids of its access tokens that have not expired yet to the
+// denylist, which the keyring checks on every request.
+
+var errUserSuspended = errors.New("user is suspended")
+
+// accessToken returns the validated access token the request was made with.
+func (cfg *ApiConfig) accessToken(r *http.Request) (auth.AccessToken, error) {
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return auth.AccessToken{}, err
+	}
+	return cfg.JWTKeys.ValidateJWT(r.Context(), tokenString)
+}
+
+// currentSessionID returns the session the request's access token was issued
+// to, or uuid.Nil if it was made with a personal access token or a token
+// issued before tokens carried their session.
+func (cfg *ApiConfig) currentSessionID(r *http.Request) uuid.UUID {
+	if _, err := auth.GetAPIKey(r.Header); err == nil {
+		return uuid.Nil
+	}
+	token, err := cfg.accessToken(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return token.SessionID
+}
+
+// createSessionTokens generates a new refresh token and access token for the
+// session described by session. The refresh token's hash is stored along
+// with the session details and the access token's id. Neither plaintext
+// token is kept anywhere.
+func (cfg *ApiConfig) createSessionTokens(r *http.Request, db *database.Queries, session database.CreateRefreshTokenParams, accessTokenDuration time.Duration) (accessToken string, refreshToken string, err error) {
+	if session.FamilyID == uuid.Nil {
+		session.FamilyID = uuid.New()
+	}
+
+	refreshToken, err = auth.MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	token := auth.AccessToken{
+		ID:        uuid.NewString(),
+		UserID:    session.UserID,
+		SessionID: session.FamilyID,
+		ExpiresAt: time.Now().Add(accessTokenDuration),
+	}
+	accessToken, err = cfg.JWTKeys.MakeJWT(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	session.TokenHash = auth.HashToken(refreshToken, cfg.TOKEN_HASH_KEY)
+	session.CreatedAt = time.Now()
+	session.UpdatedAt = time.Now()
+	session.UserAgent = r.UserAgent()
+	session.IpAddress = clientIP(r)
+	session.AccessTokenID = token.ID
+	session.AccessTokenExpiresAt = sql.NullTime{Time: token.ExpiresAt, Valid: true}
+
+	_, err = db.CreateRefreshToken(r.Context(), session)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// denyAccessTokens adds the access tokens issued alongside refreshTokens to
+// the denylist.
+func (cfg *ApiConfig) denyAccessTokens(ctx context.Context, refreshTokens []database.RefreshToken) error {
+	for _, refreshToken := range refreshTokens {
+		err := cfg.Denylist.Deny(ctx, refreshToken.AccessTokenID, refreshToken.AccessTokenExpiresAt.Time)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeSessionAccessTokens denies every live access token issued to the
+// session.
+func (cfg *ApiConfig) revokeSessionAccessTokens(ctx context.Context, sessionID uuid.UUID) error {
+	refreshTokens, err := cfg.Db.GetLiveAccessTokensByFamilyID(ctx, database.GetLiveAccessTokensByFamilyIDParams{
+		FamilyID:             sessionID,
+		AccessTokenExpiresAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.denyAccessTokens(ctx, refreshTokens)
+}
+
+// revokeUserAccessTokens denies every live access token issued to the user,
+// except those of the session keepSessionID. Pass uuid.Nil to keep none.
+func (cfg *ApiConfig) revokeUserAccessTokens(ctx context.Context, userID, keepSessionID uuid.UUID) error {
+	refreshTokens, err := cfg.Db.GetOtherLiveAccessTokensByUserID(ctx, database.GetOtherLiveAccessTokensByUserIDParams{
+		UserID:               userID,
+		FamilyID:             keepSessionID,
+		AccessTokenExpiresAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.denyAccessTokens(ctx, refreshTokens)
+}
+
+// revokeOtherSessions logs the user out of every session but keepSessionID,
+// revoking both refresh and access tokens. Pass uuid.Nil to revoke them all.
+func (cfg *ApiConfig) revokeOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) error {
+	err := cfg.Db.RevokeOtherRefreshTokensByUserID(ctx, database.RevokeOtherRefreshTokensByUserIDParams{
+		UserID:    userID,
+		FamilyID:  keepSessionID,
+		UpdatedAt: time.Now(),
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.revokeUserAccessTokens(ctx, userID, keepSessionID)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Each role can do everything the roles before it can.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	roleUser:      0,
+	roleModerator: 1,
+	roleAdmin:     2,
+}
+
+func hasRole(userRole, required string) bool {
+	rank, ok := roleRanks[userRole]
+	return ok && rank >= roleRanks[required]
+}
+
+// middlewareRequireRole only lets requests through from users with at least
+// the given role. Admin routes must be called with an access token from a
+// login, never a personal access token.
+func (cfg *ApiConfig) middlewareRequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := cfg.authenticate(r)
+		if err != nil {
+			sendTokenExpiredResponse(w)
+			return
+		}
+
+		user, err := cfg.Db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				sendTokenExpiredResponse(w)
+			} else {
+				log.Printf("error getting user from database: %v", err)
+				sendErrorResponse(w, "error checking user role")
+			}
+			return
+		}
+
+		if !hasRole(user.Role, role) {
+			log.Printf("user %v with role %q denied access to %s", user.ID, user.Role, r.URL.Path)
+			sendUserForbiddenResponse(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (cfg *ApiConfig) PutUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	params := UserRoleParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding user role params: %v", err)
+		sendErrorResponse(w, "error setting user role")
+		return
+	}
+	if _, ok := roleRanks[params.Role]; !ok {
+		sendBadRequestResponse(w, "Role must be one of user, moderator or admin")
+		return
+	}
+
+	updated, err := cfg.Db.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:        userID,
+		UpdatedAt: time.Now(),
+		Role:      params.Role,
+	})
+	if err != nil {
+		log.Printf("error setting user role: %v", err)
+		sendErrorResponse(w, "error setting user role")
+		return
+	}
+	if updated == 0 {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	sendUserRoleUpdatedResponse(w)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// buildSearchQuery turns a search box query into a to_tsquery expression.
+// Every term must match. A "quoted phrase" matches its words in order and a
+// term ending in * matches any word it is a prefix of. Anything that isn't a
+// letter or digit only separates words, so user input can never be parsed
+// as tsquery syntax. It reports false if q has no words to search for.
+func buildSearchQuery(q string) (string, bool) {
+	terms := []string{}
+	for i, part := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			if phrase := searchWords(part); len(phrase) > 0 {
+				terms = append(terms, strings.Join(phrase, " <-> "))
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			words := searchWords(field)
+			if len(words) == 0 {
+				continue
+			}
+			if strings.HasSuffix(field, "*") {
+				words[len(words)-1] += ":*"
+			}
+			terms = append(terms, strings.Join(words, " <-> "))
+		}
+	}
+	if len(terms) == 0 {
+		return "", false
+	}
+	return strings.Join(terms, " & "), true
+}
+
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchChirpsHandler finds chirps matching q, best matches first. Results
+// can be narrowed to one author by author_id or author handle.
+func (cfg *ApiConfig) SearchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tsquery, ok := buildSearchQuery(query.Get("q"))
+	if !ok {
+		sendBadRequestResponse(w, "q must contain at least one word to search for")
+		return
+	}
+
+	p, err := parsePage(query)
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if author := query.Get("author_id"); author != "" {
+		authorID.UUID, err = uuid.Parse(author)
+		if err != nil {
+			sendBadRequestResponse(w, "author_id is not a valid id")
+			return
+		}
+		authorID.Valid = true
+	} else if author := query.Get("author"); author != "" {
+		handle, ok := normalizeHandle(author)
+		if !ok {
+			sendUserNotFoundResponse(w)
+			return
+		}
+		user, err := cfg.Db.GetUserByHandle(r.Context(), handle)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				sendUserNotFoundResponse(w)
+			} else {
+				log.Printf("error getting user from database: %v", err)
+				sendErrorResponse(w, "error searching chirps")
+			}
+			return
+		}
+		authorID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	}
+
+	cursorRank, cursorID, err := p.searchCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	rows, err := cfg.Db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:      tsquery,
+		AuthorID:   authorID,
+		CursorRank: cursorRank,
+		CursorID:   cursorID,
+		Limit:      p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error searching chirps: %v", err)
+		sendErrorResponse(w, "error searching chirps")
+		return
+	}
+
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+	cfg.sendChirpPage(w, r, p, chirps, func(i int) string {
+		return searchCursor{Rank: rows[i].Rank, ID: rows[i].Chirp.ID}.String()
+	})
+}
@@ -13,18 +13,60 @@ func MakeServer(api_cfg *ApiConfig) *http.Server {
 	})
 
 	mux.Handle("GET /app/", http.StripPrefix("/app", api_cfg.AppHandler()))
-	mux.HandleFunc("GET /admin/metrics", api_cfg.MetricsHandler)
-	mux.HandleFunc("POST /admin/reset", api_cfg.ResetHandler)
+	mux.Handle("GET /admin/metrics", api_cfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(api_cfg.MetricsHandler)))
+	mux.Handle("POST /admin/reset", api_cfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(api_cfg.ResetHandler)))
+	mux.Handle("POST /admin/users/{userID}/unlock", api_cfg.middlewareRequireRole(roleModerator, http.HandlerFunc(api_cfg.PostUnlockUserHandler)))
+	mux.Handle("PUT /admin/users/{userID}/role", api_cfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(api_cfg.PutUserRoleHandler)))
+	mux.Handle("POST /admin/users/{userID}/suspend", api_cfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(api_cfg.PostSuspendUserHandler)))
+	mux.Handle("DELETE /admin/users/{userID}/suspend", api_cfg.middlewareRequireRole(roleAdmin, http.HandlerFunc(api_cfg.DeleteSuspendUserHandler)))
+	mux.HandleFunc("GET /.well-known/jwks.json", api_cfg.JWKSHandler)
 	mux.HandleFunc("GET /api/healthz", api_cfg.ReadinessHandler)
 	mux.HandleFunc("POST /api/users", api_cfg.PostUsersHandler)
 	mux.HandleFunc("PUT /api/users", api_cfg.PutUsersHandler)
+	mux.HandleFunc("GET /api/users/me", api_cfg.GetMeHandler)
+	mux.HandleFunc("PATCH /api/users/me", api_cfg.PatchUserHandler)
+	mux.HandleFunc("DELETE /api/users/me", api_cfg.DeleteUserHandler)
+	mux.HandleFunc("GET /api/users/verify", api_cfg.GetVerifyEmailHandler)
+	mux.HandleFunc("POST /api/users/verify", api_cfg.PostResendVerificationHandler)
+	mux.HandleFunc("GET /api/users/{handle}", api_cfg.GetUserProfileHandler)
+	mux.HandleFunc("GET /api/users/{user}/likes", api_cfg.GetUserLikesHandler)
+	mux.HandleFunc("POST /api/users/{user}/follow", api_cfg.PostFollowHandler)
+	mux.HandleFunc("DELETE /api/users/{user}/follow", api_cfg.DeleteFollowHandler)
+	mux.HandleFunc("GET /api/users/{user}/followers", api_cfg.GetFollowersHandler)
+	mux.HandleFunc("GET /api/users/{user}/following", api_cfg.GetFollowingHandler)
 	mux.HandleFunc("POST /api/login", api_cfg.PostLoginHandler)
+	mux.HandleFunc("POST /api/login/2fa", api_cfg.PostLoginTwoFactorHandler)
+	mux.HandleFunc("GET /api/oidc/login", api_cfg.GetOIDCLoginHandler)
+	mux.HandleFunc("GET /api/oidc/callback", api_cfg.GetOIDCCallbackHandler)
+	mux.HandleFunc("POST /api/2fa/enroll", api_cfg.PostTwoFactorEnrollHandler)
+	mux.HandleFunc("POST /api/2fa/verify", api_cfg.PostTwoFactorVerifyHandler)
+	mux.HandleFunc("DELETE /api/2fa", api_cfg.DeleteTwoFactorHandler)
+	mux.HandleFunc("POST /api/password-reset", api_cfg.PostPasswordResetHandler)
+	mux.HandleFunc("POST /api/password-reset/confirm", api_cfg.PostPasswordResetConfirmHandler)
 	mux.HandleFunc("POST /api/refresh", api_cfg.PostRefreshHandler)
 	mux.HandleFunc("POST /api/revoke", api_cfg.PostRevokeHandler)
+	mux.HandleFunc("GET /api/sessions", api_cfg.GetSessionsHandler)
+	mux.HandleFunc("DELETE /api/sessions", api_cfg.DeleteSessionsHandler)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", api_cfg.DeleteSessionHandler)
+	mux.HandleFunc("POST /api/tokens", api_cfg.PostAccessTokensHandler)
+	mux.HandleFunc("GET /api/tokens", api_cfg.GetAccessTokensHandler)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", api_cfg.DeleteAccessTokenHandler)
 	mux.HandleFunc("POST /api/chirps", api_cfg.PostChirpsHandler)
 	mux.HandleFunc("GET /api/chirps", api_cfg.GetChirpsHandler)
+	mux.HandleFunc("GET /api/chirps/search", api_cfg.SearchChirpsHandler)
+	mux.HandleFunc("GET /api/timeline", api_cfg.GetTimelineHandler)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", api_cfg.GetHashtagChirpsHandler)
+	mux.HandleFunc("GET /api/trends", api_cfg.GetTrendsHandler)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", api_cfg.GetChirpByIDHandler)
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", api_cfg.PatchChirpHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", api_cfg.DeleteChirpByIDHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", api_cfg.GetChirpRevisionsHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", api_cfg.GetChirpThreadHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", api_cfg.PostRechirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", api_cfg.DeleteRechirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", api_cfg.PostLikeHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", api_cfg.DeleteLikeHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/likes", api_cfg.GetChirpLikesHandler)
 	mux.HandleFunc("POST /api/polka/webhooks", api_cfg.PostPolkaWebhookHandler)
 
 	server := http.Server{
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// GetSessionsHandler lists the user's active sessions. A session is a refresh
+// token family: it starts at login and every refresh rotates the token inside
+// it, so the family id is used as the session id.
+func (cfg *ApiConfig) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	refreshTokens, err := cfg.Db.GetActiveRefreshTokensByUserID(r.Context(), database.GetActiveRefreshTokensByUserIDParams{
+		UserID:    userID,
+		ExpiresAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error getting sessions from database: %v", err)
+		sendErrorResponse(w, "error getting sessions")
+		return
+	}
+
+	sessions := make([]Session, len(refreshTokens))
+	for i, refreshToken := range refreshTokens {
+		sessions[i] = Session{
+			ID:         refreshToken.FamilyID,
+			CreatedAt:  refreshToken.SessionStartedAt,
+			LastUsedAt: refreshToken.CreatedAt,
+			ExpiresAt:  refreshToken.ExpiresAt.Time,
+			UserAgent:  refreshToken.UserAgent,
+			IPAddress:  refreshToken.IpAddress,
+		}
+	}
+
+	sendSessionsResponse(w, sessions)
+}
+
+func (cfg *ApiConfig) DeleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		sendSessionNotFoundResponse(w)
+		return
+	}
+
+	revoked, err := cfg.Db.RevokeRefreshTokenFamilyForUser(r.Context(), database.RevokeRefreshTokenFamilyForUserParams{
+		FamilyID:  sessionID,
+		UserID:    userID,
+		UpdatedAt: time.Now(),
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error revoking session in database: %v", err)
+		sendErrorResponse(w, "error revoking session")
+		return
+	}
+
+	if revoked == 0 {
+		sendSessionNotFoundResponse(w)
+		return
+	}
+
+	err = cfg.revokeSessionAccessTokens(r.Context(), sessionID)
+	if err != nil {
+		log.Printf("error revoking session access tokens: %v", err)
+		sendErrorResponse(w, "error revoking session")
+		return
+	}
+
+	sendSessionRevokedResponse(w)
+}
+
+// DeleteSessionsHandler logs the user out everywhere by revoking every one of
+// their refresh tokens and access tokens.
+func (cfg *ApiConfig) DeleteSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	err = cfg.revokeOtherSessions(r.Context(), userID, uuid.Nil)
+	if err != nil {
+		log.Printf("error revoking sessions in database: %v", err)
+		sendErrorResponse(w, "error revoking sessions")
+		return
+	}
+
+	sendSessionRevokedResponse(w)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// PostSuspendUserHandler stops a user from logging in or refreshing tokens
+// and ends every session they have, including access tokens that have not
+// expired yet. Their personal access tokens stop working until the
+// suspension is lifted.
+func (cfg *ApiConfig) PostSuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	updated, err := cfg.Db.SetUserSuspended(r.Context(), database.SetUserSuspendedParams{
+		ID:          userID,
+		UpdatedAt:   time.Now(),
+		SuspendedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error suspending user: %v", err)
+		sendErrorResponse(w, "error suspending user")
+		return
+	}
+	if updated == 0 {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	err = cfg.revokeOtherSessions(r.Context(), userID, uuid.Nil)
+	if err != nil {
+		log.Printf("error revoking sessions of suspended user %v: %v", userID, err)
+		sendErrorResponse(w, "error suspending user")
+		return
+	}
+
+	log.Printf("suspended user %v", userID)
+	sendUserSuspensionUpdatedResponse(w)
+}
+
+func (cfg *ApiConfig) DeleteSuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	updated, err := cfg.Db.SetUserSuspended(r.Context(), database.SetUserSuspendedParams{
+		ID:        userID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error lifting user suspension: %v", err)
+		sendErrorResponse(w, "error lifting user suspension")
+		return
+	}
+	if updated == 0 {
+		sendUserNotFoundResponse(w)
+		return
+	}
+
+	sendUserSuspensionUpdatedResponse(w)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxThreadAncestors is how many chirps up the reply chain a thread
+	// shows. Clients can open the thread of the topmost one to see more.
+	maxThreadAncestors = 50
+
+	defaultThreadDepth = 3
+	maxThreadDepth     = 10
+
+	// maxThreadDescendants caps the nested replies loaded below a page of
+	// replies, however wide the tree is.
+	maxThreadDescendants = 500
+)
+
+// deleteChirp deletes a chirp, or leaves a tombstone in its place if it has
+// replies or quotes so they can still show what they were responding to.
+// Rechirps of it are deleted either way.
+func (cfg *ApiConfig) deleteChirp(ctx context.Context, chirpID uuid.UUID) error {
+	tx, err := cfg.SqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	// Locking the chirp holds off replies to it until the delete commits.
+	_, err = qtx.GetChirpForUpdate(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+
+	referenced, err := qtx.ChirpHasRepliesOrQuotes(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if referenced {
+		err = qtx.DeleteRechirpsOf(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+		if err != nil {
+			return err
+		}
+		err = qtx.DeleteChirpRevisions(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		err = qtx.DeleteChirpHashtags(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		err = qtx.TombstoneChirp(ctx, database.TombstoneChirpParams{
+			ID:        chirpID,
+			UpdatedAt: time.Now(),
+		})
+	} else {
+		err = qtx.DeleteChirpByID(ctx, chirpID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetChirpThreadHandler returns a chirp with the chain of chirps it replies
+// to and a page of its replies. Each reply carries its own replies down to
+// depth levels below the chirp. Deleted chirps and chirps of users pending
+// deletion show up as tombstones so the thread stays connected.
+func (cfg *ApiConfig) GetChirpThreadHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+
+	query := r.URL.Query()
+	p, err := parsePage(query)
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	depth := defaultThreadDepth
+	if s := query.Get("depth"); s != "" {
+		depth, err = strconv.Atoi(s)
+		if err != nil || depth < 1 || depth > maxThreadDepth {
+			sendInvalidThreadDepthResponse(w)
+			return
+		}
+	}
+
+	cursorCreatedAt, cursorID, err := p.chirpCursorArgs()
+	if err != nil {
+		sendInvalidPageResponse(w)
+		return
+	}
+
+	ancestorRows, err := cfg.Db.GetChirpWithAncestors(r.Context(), database.GetChirpWithAncestorsParams{
+		ID:       chirpID,
+		MaxDepth: maxThreadAncestors + 1,
+	})
+	if err != nil {
+		log.Printf("error getting chirp thread from database: %v", err)
+		sendErrorResponse(w, "error getting thread")
+		return
+	}
+	if len(ancestorRows) == 0 {
+		sendChirpNotFoundResponse(w)
+		return
+	}
+	moreAncestors := ancestorRows[0].Depth > maxThreadAncestors
+	if moreAncestors {
+		ancestorRows = ancestorRows[1:]
+	}
+
+	replyRows, err := cfg.Db.ListChirpReplies(r.Context(), database.ListChirpRepliesParams{
+		ParentChirpID:   chirpID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("error getting chirp replies from database: %v", err)
+		sendErrorResponse(w, "error getting thread")
+		return
+	}
+	var nextCursor *string
+	if len(replyRows) > p.Limit {
+		replyRows = replyRows[:p.Limit]
+		last := replyRows[len(replyRows)-1]
+		cursor := chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+		nextCursor = &cursor
+	}
+
+	// Everything in the thread is converted at once so author profiles are
+	// loaded with a single query.
+	chirps := []database.Chirp{}
+	hasReplies := map[uuid.UUID]bool{}
+	for _, row := range ancestorRows {
+		chirps = append(chirps, threadChirp(row.ID, row.CreatedAt, row.UpdatedAt, row.Body, row.UserID, row.ParentChirpID, row.RootChirpID, row.QuoteOfID, row.DeletedAt))
+	}
+	for _, row := range replyRows {
+		chirps = append(chirps, threadChirp(row.ID, row.CreatedAt, row.UpdatedAt, row.Body, row.UserID, row.ParentChirpID, row.RootChirpID, row.QuoteOfID, row.DeletedAt))
+		hasReplies[row.ID] = row.HasReplies
+	}
+
+	truncated := false
+	if depth > 1 && len(replyRows) > 0 {
+		parentIDs := make([]uuid.UUID, len(replyRows))
+		for i, row := range replyRows {
+			parentIDs[i] = row.ID
+		}
+		descendantRows, err := cfg.Db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+			ParentIds: parentIDs,
+			MaxDepth:  int32(depth - 1),
+			Limit:     maxThreadDescendants,
+		})
+		if err != nil {
+			log.Printf("error getting chirp replies from database: %v", err)
+			sendErrorResponse(w, "error getting thread")
+			return
+		}
+		truncated = len(descendantRows) == maxThreadDescendants
+		for _, row := range descendantRows {
+			chirps = append(chirps, threadChirp(row.ID, row.CreatedAt, row.UpdatedAt, row.Body, row.UserID, row.ParentChirpID, row.RootChirpID, row.QuoteOfID, row.DeletedAt))
+			hasReplies[row.ID] = row.HasReplies
+		}
+	}
+
+	api_chirps, err := cfg.chirpsFromDB(r.Context(), cfg.viewer(r), chirps)
+	if err != nil {
+		log.Printf("error getting chirp authors: %v", err)
+		sendErrorResponse(w, "error getting thread")
+		return
+	}
+
+	children := map[uuid.UUID][]Chirp{}
+	for _, chirp := range api_chirps[len(ancestorRows):] {
+		children[*chirp.InReplyTo] = append(children[*chirp.InReplyTo], chirp)
+	}
+	var replyTree func(chirps []Chirp) []ThreadReply
+	replyTree = func(chirps []Chirp) []ThreadReply {
+		replies := make([]ThreadReply, len(chirps))
+		for i, chirp := range chirps {
+			replies[i] = ThreadReply{
+				Chirp:   chirp,
+				Replies: replyTree(children[chirp.ID]),
+			}
+			replies[i].MoreReplies = hasReplies[chirp.ID] && (len(replies[i].Replies) == 0 || truncated)
+		}
+		return replies
+	}
+
+	focal := len(ancestorRows) - 1
+	if nextCursor != nil {
+		setNextPageLink(w, r, *nextCursor)
+	}
+	sendChirpThreadResponse(w, ChirpThread{
+		Ancestors:     api_chirps[:focal],
+		MoreAncestors: moreAncestors,
+		Chirp:         api_chirps[focal],
+		Replies:       replyTree(children[chirpID]),
+		NextCursor:    nextCursor,
+	})
+}
+
+func threadChirp(id uuid.UUID, createdAt, updatedAt time.Time, body string, userID uuid.UUID, parentID, rootID, quoteOfID uuid.NullUUID, deletedAt sql.NullTime) database.Chirp {
+	return database.Chirp{
+		ID:            id,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+		Body:          body,
+		UserID:        userID,
+		ParentChirpID: parentID,
+		RootChirpID:   rootID,
+		QuoteOfID:     quoteOfID,
+		DeletedAt:     deletedAt,
+	}
+}
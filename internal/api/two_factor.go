@@ -0,0 +1,371 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	twoFactorChallengeDuration = 5 * time.Minute
+	recoveryCodeCount          = 10
+)
+
+// sendTwoFactorChallenge responds to a login by a user with 2FA enabled with
+// a challenge token to be exchanged at PostLoginTwoFactorHandler.
+func (cfg *ApiConfig) sendTwoFactorChallenge(w http.ResponseWriter, user database.User) {
+	challengeToken, err := cfg.JWTKeys.MakeChallengeJWT(user.ID, twoFactorChallengeDuration)
+	if err != nil {
+		log.Printf("error creating two-factor challenge: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+
+	sendTwoFactorChallengeResponse(w, TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+	})
+}
+
+// checkSecondFactor reports whether params holds a valid TOTP code or an
+// unused recovery code for user. Both are single-use: a TOTP code is
+// rejected if its time step has already been used.
+func (cfg *ApiConfig) checkSecondFactor(ctx context.Context, user database.User, params TwoFactorCodeParams) (bool, error) {
+	if params.Code != "" {
+		if !user.TotpSecret.Valid {
+			return false, nil
+		}
+		step, ok := auth.VerifyTOTP(user.TotpSecret.String, params.Code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		used, err := cfg.Db.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{
+			ID:               user.ID,
+			TotpLastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	if params.RecoveryCode != "" {
+		used, err := cfg.Db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(params.RecoveryCode), cfg.TOKEN_HASH_KEY),
+			UsedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	return false, nil
+}
+
+func (cfg *ApiConfig) PostTwoFactorEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error enrolling two-factor authentication")
+		}
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		sendTwoFactorAlreadyEnabledResponse(w)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("error generating totp secret: %v", err)
+		sendErrorResponse(w, "error enrolling two-factor authentication")
+		return
+	}
+
+	// The secret stays pending until the user proves their authenticator
+	// app produces matching codes.
+	err = cfg.Db.SetUserTOTPSecret(r.Context(), database.SetUserTOTPSecretParams{
+		ID:         user.ID,
+		UpdatedAt:  time.Now(),
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		log.Printf("error saving totp secret: %v", err)
+		sendErrorResponse(w, "error enrolling two-factor authentication")
+		return
+	}
+
+	sendTwoFactorEnrollmentResponse(w, TwoFactorEnrollment{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(secret, "Chirpy", user.Email),
+	})
+}
+
+func (cfg *ApiConfig) PostTwoFactorVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	params := TwoFactorCodeParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding two-factor params: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error verifying two-factor authentication")
+		}
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		sendTwoFactorAlreadyEnabledResponse(w)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		sendBadRequestResponse(w, "Two-factor enrollment has not been started")
+		return
+	}
+
+	// Recovery codes only exist once 2FA is enabled, so only a TOTP code
+	// can confirm enrollment.
+	ok, err := cfg.checkSecondFactor(r.Context(), user, TwoFactorCodeParams{Code: params.Code})
+	if err != nil {
+		log.Printf("error checking totp code: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+	if !ok {
+		sendInvalidTwoFactorCodeResponse(w)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		log.Printf("error generating recovery codes: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting two-factor transaction: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	err = qtx.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+		ID:            user.ID,
+		UpdatedAt:     time.Now(),
+		TotpEnabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error enabling totp: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+
+	err = qtx.DeleteRecoveryCodesByUserID(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("error deleting old recovery codes: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+
+	for _, code := range codes {
+		err = qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			ID:        uuid.New(),
+			UserID:    user.ID,
+			CodeHash:  auth.HashToken(auth.NormalizeRecoveryCode(code), cfg.TOKEN_HASH_KEY),
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Printf("error saving recovery code: %v", err)
+			sendErrorResponse(w, "error verifying two-factor authentication")
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing two-factor transaction: %v", err)
+		sendErrorResponse(w, "error verifying two-factor authentication")
+		return
+	}
+
+	sendRecoveryCodesResponse(w, RecoveryCodes{RecoveryCodes: codes})
+}
+
+func (cfg *ApiConfig) DeleteTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	params := TwoFactorCodeParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding two-factor params: %v", err)
+		sendErrorResponse(w, "error disabling two-factor authentication")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error disabling two-factor authentication")
+		}
+		return
+	}
+
+	if !user.TotpEnabledAt.Valid {
+		sendTwoFactorNotEnabledResponse(w)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), user, params)
+	if err != nil {
+		log.Printf("error checking second factor: %v", err)
+		sendErrorResponse(w, "error disabling two-factor authentication")
+		return
+	}
+	if !ok {
+		sendInvalidTwoFactorCodeResponse(w)
+		return
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting two-factor transaction: %v", err)
+		sendErrorResponse(w, "error disabling two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	err = qtx.DisableUserTOTP(r.Context(), database.DisableUserTOTPParams{
+		ID:        user.ID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error disabling totp: %v", err)
+		sendErrorResponse(w, "error disabling two-factor authentication")
+		return
+	}
+
+	err = qtx.DeleteRecoveryCodesByUserID(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("error deleting recovery codes: %v", err)
+		sendErrorResponse(w, "error disabling two-factor authentication")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing two-factor transaction: %v", err)
+		sendErrorResponse(w, "error disabling two-factor authentication")
+		return
+	}
+
+	sendTwoFactorDisabledResponse(w)
+}
+
+// PostLoginTwoFactorHandler completes a login started by PostLoginHandler
+// for a user with 2FA enabled, exchanging the challenge token and a second
+// factor for an access token and refresh token.
+func (cfg *ApiConfig) PostLoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	params := TwoFactorLoginParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding two-factor login params: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+
+	ip := clientIP(r)
+	if retryAfter := cfg.loginLimiter.retryAfter(ip, time.Now()); retryAfter > 0 {
+		sendTooManyLoginAttemptsResponse(w, retryAfter)
+		return
+	}
+
+	userID, err := cfg.JWTKeys.ValidateChallengeJWT(params.ChallengeToken)
+	if err != nil {
+		sendTokenExpiredResponse(w)
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendUserNotFoundResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error logging in")
+		}
+		return
+	}
+
+	if retryAfter := accountRetryAfter(user, time.Now()); retryAfter > 0 {
+		sendTooManyLoginAttemptsResponse(w, retryAfter)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), user, TwoFactorCodeParams{
+		Code:         params.Code,
+		RecoveryCode: params.RecoveryCode,
+	})
+	if err != nil {
+		log.Printf("error checking second factor: %v", err)
+		sendErrorResponse(w, "error logging in")
+		return
+	}
+	if !ok {
+		err = cfg.recordFailedLogin(r.Context(), ip, user)
+		if err != nil {
+			log.Printf("error recording failed login: %v", err)
+		}
+		sendInvalidTwoFactorCodeResponse(w)
+		return
+	}
+
+	err = cfg.recordSuccessfulLogin(r.Context(), user)
+	if err != nil {
+		log.Printf("error clearing failed logins: %v", err)
+	}
+
+	cfg.finishLogin(w, r, user)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/auth"
+	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PatchUserHandler updates only the fields present in the request. Changing
+// the email or password needs the current password, and a new password
+// ends every session but the one the request was made from. Profile fields
+// can be changed without it.
+func (cfg *ApiConfig) PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authorize(r, scopeProfileWrite)
+	if err != nil {
+		sendAuthorizationErrorResponse(w, err)
+		return
+	}
+
+	params := UserPatchParams{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		log.Printf("error decoding user update params: %v", err)
+		sendBadRequestResponse(w, "Request body is not valid JSON")
+		return
+	}
+
+	cfg.updateUser(w, r, userID, params)
+}
+
+// updateUser applies a user update and sends the response. Fields left nil
+// in params are not changed.
+func (cfg *ApiConfig) updateUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID, params UserPatchParams) {
+	user, err := cfg.Db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendTokenExpiredResponse(w)
+		} else {
+			log.Printf("error getting user from database: %v", err)
+			sendErrorResponse(w, "error updating user")
+		}
+		return
+	}
+
+	changeEmail := params.Email != nil && *params.Email != user.Email
+	changePassword := params.Password != nil
+	changeProfile := params.Handle != nil || params.DisplayName != nil || params.Bio != nil
+	if !changeEmail && !changePassword && !changeProfile {
+		sendUpdatedUser(w, userFromDB(user))
+		return
+	}
+
+	if changeEmail && !validEmail(*params.Email) {
+		sendInvalidEmailResponse(w)
+		return
+	}
+
+	profile := database.UpdateUserProfileParams{
+		ID:          user.ID,
+		UpdatedAt:   time.Now(),
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+	}
+	if params.Handle != nil {
+		handle, ok := normalizeHandle(*params.Handle)
+		if !ok {
+			sendInvalidHandleResponse(w)
+			return
+		}
+		profile.Handle = handle
+	}
+	if params.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*params.DisplayName)
+	}
+	if params.Bio != nil {
+		profile.Bio = strings.TrimSpace(*params.Bio)
+	}
+	if !validProfileText(profile.DisplayName, profile.Bio) {
+		sendProfileTooLongResponse(w)
+		return
+	}
+	email := user.Email
+	if changeEmail {
+		email = *params.Email
+	}
+	if changePassword && !cfg.acceptablePassword(w, *params.Password, email) {
+		return
+	}
+
+	if (changeEmail || changePassword) && !cfg.confirmPassword(w, r, user, params.CurrentPassword) {
+		return
+	}
+
+	tx, err := cfg.SqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting user update transaction: %v", err)
+		sendErrorResponse(w, "error updating user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	if changeEmail {
+		user, err = qtx.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+			ID:        user.ID,
+			UpdatedAt: time.Now(),
+			Email:     email,
+		})
+		if err != nil {
+			if isUniqueViolation(err, "users_email_key") {
+				sendEmailInUseResponse(w)
+			} else {
+				log.Printf("error updating email: %v", err)
+				sendErrorResponse(w, "error updating user")
+			}
+			return
+		}
+	}
+
+	if changePassword {
+		hashedPassword, err := cfg.Passwords.Hash(*params.Password)
+		if err != nil {
+			log.Printf("error hashing password: %v", err)
+			sendErrorResponse(w, "error updating user")
+			return
+		}
+		err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             user.ID,
+			UpdatedAt:      time.Now(),
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			log.Printf("error updating password: %v", err)
+			sendErrorResponse(w, "error updating user")
+			return
+		}
+	}
+
+	if changeProfile {
+		user, err = qtx.UpdateUserProfile(r.Context(), profile)
+		if err != nil {
+			if isUniqueViolation(err, "users_handle_key") {
+				sendHandleInUseResponse(w)
+			} else {
+				log.Printf("error updating profile: %v", err)
+				sendErrorResponse(w, "error updating user")
+			}
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error committing user update: %v", err)
+		sendErrorResponse(w, "error updating user")
+		return
+	}
+
+	if changePassword {
+		err = cfg.revokeOtherSessions(r.Context(), user.ID, cfg.currentSessionID(r))
+		if err != nil {
+			log.Printf("error revoking other sessions: %v", err)
+			sendErrorResponse(w, "error updating user")
+			return
+		}
+	}
+
+	if changeEmail {
+		err = cfg.sendVerificationEmail(r.Context(), user)
+		if err != nil {
+			log.Printf("error sending verification email: %v", err)
+		}
+	}
+
+	sendUpdatedUser(w, userFromDB(user))
+}
+
+// confirmPassword checks the password the user re-entered to confirm a
+// sensitive change. It counts towards the login lockout like a login, so a
+// stolen access token can't be used to guess it. If the password is wrong
+// the response has already been sent.
+func (cfg *ApiConfig) confirmPassword(w http.ResponseWriter, r *http.Request, user database.User, password string) bool {
+	if retryAfter := accountRetryAfter(user, time.Now()); retryAfter > 0 {
+		sendTooManyLoginAttemptsResponse(w, retryAfter)
+		return false
+	}
+
+	_, err := cfg.Passwords.Verify(password, user.HashedPassword)
+	if err != nil {
+		if err != auth.ErrPasswordMismatch {
+			log.Printf("error verifying password: %v", err)
+		}
+		err = cfg.recordFailedLogin(r.Context(), clientIP(r), user)
+		if err != nil {
+			log.Printf("error recording failed login: %v", err)
+		}
+		sendInvalidCredentialsResponse(w)
+		return false
+	}
+	return true
+}
+
+// isUniqueViolation reports whether err is postgres refusing a write that
+// breaks the named unique constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
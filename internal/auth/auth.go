@@ -1,75 +1,21 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	cost := 10
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	if err != nil {
-		return "", err
-	}
-	return string(hash), nil
-}
-
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-}
-
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
-
-	s, err := t.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return s, nil
-}
-
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := jwt.RegisteredClaims{}
-	t, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(tokenSecret), nil
-	})
-
-	if err != nil || !t.Valid {
-		return uuid.Nil, errors.New("invalid token received")
-	}
-
-	userId, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	return userId, nil
-}
-
 func GetBearerToken(headers http.Header) (string, error) {
 	tokens := headers.Values("Authorization")
 	for _, tokenStr := range tokens {
 		words := strings.Fields(tokenStr)
-		if strings.ToLower(words[0]) == "bearer" && len(words) == 2 {
+		if len(words) == 2 && strings.ToLower(words[0]) == "bearer" {
 			return words[1], nil
 		}
 	}
@@ -86,11 +32,30 @@ func MakeRefreshToken() (string, error) {
 	return hexStr, nil
 }
 
+// MakeSecureToken returns a random hex encoded token for single-use links
+// such as password resets.
+func MakeSecureToken() (string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken returns the keyed hash of an opaque token. Only the hash is
+// stored, so a leaked database cannot be used to replay tokens.
+func HashToken(token, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	tokens := headers.Values("Authorization")
 	for _, tokenStr := range tokens {
 		words := strings.Fields(tokenStr)
-		if strings.ToLower(words[0]) == "apikey" && len(words) == 2 {
+		if len(words) == 2 && strings.ToLower(words[0]) == "apikey" {
 			return words[1], nil
 		}
 	}
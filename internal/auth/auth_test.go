@@ -3,63 +3,42 @@ package auth
 import (
 	"net/http"
 	"testing"
-	"time"
-
-	"github.com/google/uuid"
 )
 
-func TestJWT_Correct(t *testing.T) {
-	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
-	secret := "test-secret"
-	token, err := MakeJWT(userId, secret, time.Minute)
+func TestGetBearerToken(t *testing.T) {
+	token, err := MakeRefreshToken()
 	if err != nil {
-		t.Error("token creation failed")
+		t.Fatalf("token creation failed")
 	}
+	req, _ := http.NewRequest(http.MethodGet, "https://localhost:8080/", nil)
+	req.Header.Add("Authorization", "Bearer "+token)
 
-	gotId, err := ValidateJWT(token, secret)
+	bearerToken, err := GetBearerToken(req.Header)
 	if err != nil {
-		t.Fatalf("validation failed, err not nil")
+		t.Fatalf("error parsing bearer token")
 	}
 
-	if gotId != userId {
-		t.Fatalf("validation failed, wrong id in token, %v", gotId)
+	if bearerToken != token {
+		t.Fatalf("bearer token does not match jwt token")
 	}
 }
 
-func TestJWT_Expired(t *testing.T) {
-	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
-	secret := "test-secret"
-	token, err := MakeJWT(userId, secret, -1*time.Second)
+func TestHashToken(t *testing.T) {
+	token, err := MakeRefreshToken()
 	if err != nil {
 		t.Fatalf("token creation failed")
 	}
 
-	gotId, err := ValidateJWT(token, secret)
-	if err == nil {
-		t.Fatalf("validation failed, err is nil for expired token")
+	hash := HashToken(token, "test-key")
+	if hash == token {
+		t.Fatalf("hash matches plaintext token")
 	}
 
-	if gotId != uuid.Nil {
-		t.Fatalf("validation failed, wrong id in token, %v", gotId)
+	if HashToken(token, "test-key") != hash {
+		t.Fatalf("hashing the same token twice gave different results")
 	}
-}
 
-func TestGetBearerToken(t *testing.T) {
-	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
-	secret := "test-secret"
-	token, err := MakeJWT(userId, secret, time.Minute)
-	if err != nil {
-		t.Error("token creation failed")
-	}
-	req, _ := http.NewRequest(http.MethodGet, "https://localhost:8080/", nil)
-	req.Header.Add("Authorization", "Bearer "+token)
-
-	bearerToken, err := GetBearerToken(req.Header)
-	if err != nil {
-		t.Fatalf("error parsing bearer token")
-	}
-
-	if bearerToken != token {
-		t.Fatalf("bearer token does not match jwt token")
+	if HashToken(token, "other-key") == hash {
+		t.Fatalf("hash does not depend on the key")
 	}
 }
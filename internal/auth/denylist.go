@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/database"
+)
+
+// Denylist holds the ids of access tokens revoked before they expire. An
+// entry only needs to be kept until the token it names would have expired
+// anyway.
+type Denylist interface {
+	Deny(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsDenied(ctx context.Context, tokenID string) (bool, error)
+}
+
+// MemoryDenylist keeps denied token ids in memory. It is only suitable for a
+// single server, and forgets every entry on restart.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: make(map[string]time.Time)}
+}
+
+func (d *MemoryDenylist) Deny(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range d.entries {
+		if !now.Before(exp) {
+			delete(d.entries, id)
+		}
+	}
+	if now.Before(expiresAt) {
+		d.entries[tokenID] = expiresAt
+	}
+	return nil
+}
+
+func (d *MemoryDenylist) IsDenied(ctx context.Context, tokenID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	exp, ok := d.entries[tokenID]
+	return ok && time.Now().Before(exp), nil
+}
+
+// DBDenylist keeps denied token ids in the database so every server sees
+// them.
+type DBDenylist struct {
+	db *database.Queries
+}
+
+func NewDBDenylist(db *database.Queries) *DBDenylist {
+	return &DBDenylist{db: db}
+}
+
+func (d *DBDenylist) Deny(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	err := d.db.DenyAccessToken(ctx, database.DenyAccessTokenParams{
+		TokenID:   tokenID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	return d.db.DeleteExpiredDeniedAccessTokens(ctx, time.Now())
+}
+
+func (d *DBDenylist) IsDenied(ctx context.Context, tokenID string) (bool, error) {
+	return d.db.IsAccessTokenDenied(ctx, tokenID)
+}
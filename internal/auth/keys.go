@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// SigningKey is a single key in a Keyring. Keys loaded from a public key
+// can only verify tokens; keys loaded from a private key or an HMAC secret
+// can also sign them.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// NewHMACKey returns an HS256 key. It exists so tokens signed with the old
+// shared JWT_SECRET keep working while asymmetric keys are rolled out.
+func NewHMACKey(id, secret string) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// LoadSigningKey reads a PEM encoded RSA or Ed25519 key from disk. A private
+// key gives a key that can sign, a public key gives a verification-only key.
+func LoadSigningKey(id, path string) (*SigningKey, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file for %q: %w", id, err)
+	}
+	return ParseSigningKeyPEM(id, bytes)
+}
+
+func ParseSigningKeyPEM(id string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found for key %q", id)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q for key %q", block.Type, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing key %q: %w", id, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, verifyKey: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T for key %q", parsed, id)
+	}
+}
+
+// Keyring signs access tokens with one active key and verifies them with any
+// of its keys, selected by the token's kid header. Rotating keys is done by
+// adding a new key, making it active, and removing the old one once every
+// token it signed has expired.
+type Keyring struct {
+	active   *SigningKey
+	keys     map[string]*SigningKey
+	denylist Denylist
+}
+
+func NewKeyring(activeKeyID string, keys ...*SigningKey) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+
+	active, ok := k.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeKeyID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %q cannot sign tokens", activeKeyID)
+	}
+	k.active = active
+
+	return k, nil
+}
+
+// AccessAudience is the audience of every access token. Services verifying
+// access tokens against the JWKS must require it, since the same keys sign
+// other kinds of tokens. Tokens signed with the legacy JWT_SECRET before it
+// was added have no audience and are still accepted.
+const AccessAudience = "chirpy-api"
+
+// twoFactorAudience marks the short-lived challenge tokens handed out after
+// a correct password when the user still has to provide a second factor.
+// They must never be accepted as access tokens.
+const twoFactorAudience = "chirpy-2fa"
+
+// UseDenylist makes ValidateJWT reject access tokens whose id has been
+// added to d.
+func (k *Keyring) UseDenylist(d Denylist) {
+	k.denylist = d
+}
+
+// AccessToken describes a signed access token. ID is its jti claim, used to
+// revoke it before it expires, and SessionID the session it was issued to.
+// Tokens issued before these were added have neither.
+type AccessToken struct {
+	ID        string
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	ExpiresAt time.Time
+}
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+}
+
+func (k *Keyring) MakeJWT(token AccessToken) (string, error) {
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Audience:  jwt.ClaimStrings{AccessAudience},
+			ID:        token.ID,
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(token.ExpiresAt.UTC()),
+			Subject:   token.UserID.String(),
+		},
+	}
+	if token.SessionID != uuid.Nil {
+		claims.SessionID = token.SessionID.String()
+	}
+	return k.signJWT(claims)
+}
+
+func (k *Keyring) ValidateJWT(ctx context.Context, tokenString string) (AccessToken, error) {
+	claims := accessClaims{}
+	t, err := k.parseJWT(tokenString, &claims)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	if slices.Contains(claims.Audience, twoFactorAudience) {
+		return AccessToken{}, errors.New("challenge token used as access token")
+	}
+	if !slices.Contains(claims.Audience, AccessAudience) && !(len(claims.Audience) == 0 && k.isLegacyKey(t)) {
+		return AccessToken{}, errors.New("token is not an access token")
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	token := AccessToken{
+		ID:        claims.ID,
+		UserID:    userID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if claims.SessionID != "" {
+		token.SessionID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return AccessToken{}, err
+		}
+	}
+
+	if k.denylist != nil && token.ID != "" {
+		denied, err := k.denylist.IsDenied(ctx, token.ID)
+		if err != nil {
+			return AccessToken{}, fmt.Errorf("error checking denylist: %w", err)
+		}
+		if denied {
+			return AccessToken{}, errors.New("access token has been revoked")
+		}
+	}
+
+	return token, nil
+}
+
+// MakeChallengeJWT returns a token proving the user passed the password
+// check, to be exchanged for real tokens along with a second factor.
+func (k *Keyring) MakeChallengeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.signJWT(jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{twoFactorAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+	})
+}
+
+func (k *Keyring) ValidateChallengeJWT(tokenString string) (uuid.UUID, error) {
+	claims := jwt.RegisteredClaims{}
+	_, err := k.parseJWT(tokenString, &claims, jwt.WithAudience(twoFactorAudience))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return uuid.Parse(claims.Subject)
+}
+
+func (k *Keyring) signJWT(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(k.active.Method, claims)
+	if k.active.ID != "" {
+		t.Header["kid"] = k.active.ID
+	}
+
+	return t.SignedString(k.active.signKey)
+}
+
+func (k *Keyring) parseJWT(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	opts = append(opts, jwt.WithIssuer("chirpy"), jwt.WithExpirationRequired())
+	t, err := jwt.ParseWithClaims(tokenString, claims, k.keyFunc, opts...)
+	if err != nil || !t.Valid {
+		return nil, errors.New("invalid token received")
+	}
+
+	return t, nil
+}
+
+// isLegacyKey reports whether t was verified with the JWT_SECRET key, which
+// has no id. Access tokens it signed before audiences were added have none.
+func (k *Keyring) isLegacyKey(t *jwt.Token) bool {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	return ok && kid == "" && key.Method.Alg() == jwt.SigningMethodHS256.Alg()
+}
+
+func (k *Keyring) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric key in the keyring so
+// other services can verify access tokens. HMAC keys are never published.
+func (k *Keyring) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func makeTestKeyPEM(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func makeTestRSAKey(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating rsa key: %v", err)
+	}
+	key, err := ParseSigningKeyPEM(id, makeTestKeyPEM(t, rsaKey))
+	if err != nil {
+		t.Fatalf("error parsing rsa key: %v", err)
+	}
+	return key
+}
+
+func makeTestEd25519Key(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating ed25519 key: %v", err)
+	}
+	key, err := ParseSigningKeyPEM(id, makeTestKeyPEM(t, edKey))
+	if err != nil {
+		t.Fatalf("error parsing ed25519 key: %v", err)
+	}
+	return key
+}
+
+func TestKeyring_SignAndValidate(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	keys := []*SigningKey{
+		makeTestRSAKey(t, "rsa-1"),
+		makeTestEd25519Key(t, "ed-1"),
+	}
+
+	for _, key := range keys {
+		keyring, err := NewKeyring(key.ID, key)
+		if err != nil {
+			t.Fatalf("error creating keyring: %v", err)
+		}
+
+		token, err := keyring.MakeJWT(AccessToken{UserID: userId, ExpiresAt: time.Now().Add(time.Minute)})
+		if err != nil {
+			t.Fatalf("token creation failed for %s: %v", key.ID, err)
+		}
+
+		got, err := keyring.ValidateJWT(context.Background(), token)
+		if err != nil {
+			t.Fatalf("validation failed for %s: %v", key.ID, err)
+		}
+		if got.UserID != userId {
+			t.Fatalf("validation failed, wrong id in token, %v", got.UserID)
+		}
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	oldKey := makeTestEd25519Key(t, "old")
+	newKey := makeTestEd25519Key(t, "new")
+
+	oldKeyring, err := NewKeyring("old", oldKey)
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+	token, err := oldKeyring.MakeJWT(AccessToken{UserID: userId, ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("token creation failed")
+	}
+
+	rotated, err := NewKeyring("new", oldKey, newKey)
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+	if _, err := rotated.ValidateJWT(context.Background(), token); err != nil {
+		t.Fatalf("token signed by previous key rejected after rotation")
+	}
+
+	retired, err := NewKeyring("new", newKey)
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+	if _, err := retired.ValidateJWT(context.Background(), token); err == nil {
+		t.Fatalf("token signed by removed key was accepted")
+	}
+}
+
+func TestKeyring_LegacyHS256(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	secret := "test-secret"
+	// Tokens from before key rotation were HS256 with no key id.
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Minute)),
+		Subject:   userId.String(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("token creation failed")
+	}
+
+	keyring, err := NewKeyring("ed-1", makeTestEd25519Key(t, "ed-1"), NewHMACKey("", secret))
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+
+	got, err := keyring.ValidateJWT(context.Background(), token)
+	if err != nil {
+		t.Fatalf("legacy token rejected: %v", err)
+	}
+	if got.UserID != userId {
+		t.Fatalf("validation failed, wrong id in token, %v", got.UserID)
+	}
+}
+
+func TestKeyring_LegacyHS256Challenge(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	keyring, err := NewKeyring("", NewHMACKey("", "test-secret"))
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+
+	challenge, err := keyring.MakeChallengeJWT(userId, time.Minute)
+	if err != nil {
+		t.Fatalf("challenge creation failed")
+	}
+	if _, err := keyring.ValidateJWT(context.Background(), challenge); err == nil {
+		t.Fatalf("challenge token signed by legacy key accepted as access token")
+	}
+}
+
+func TestKeyring_VerificationOnlyKey(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	if err != nil {
+		t.Fatalf("error marshalling public key: %v", err)
+	}
+	key, err := ParseSigningKeyPEM("pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("error parsing public key: %v", err)
+	}
+
+	if _, err := NewKeyring("pub", key); err == nil {
+		t.Fatalf("keyring accepted a public key as the active signing key")
+	}
+}
+
+func TestKeyring_JWKS(t *testing.T) {
+	keyring, err := NewKeyring("rsa-1",
+		makeTestRSAKey(t, "rsa-1"),
+		makeTestEd25519Key(t, "ed-1"),
+		NewHMACKey("", "test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+
+	jwks := keyring.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 published keys, got %d", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Kid != "ed-1" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].X == "" {
+		t.Fatalf("unexpected ed25519 jwk: %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kid != "rsa-1" || jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].N == "" || jwks.Keys[1].E != "AQAB" {
+		t.Fatalf("unexpected rsa jwk: %+v", jwks.Keys[1])
+	}
+}
+
+func TestKeyring_ChallengeTokens(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	keyring, err := NewKeyring("ed-1", makeTestEd25519Key(t, "ed-1"))
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+
+	challenge, err := keyring.MakeChallengeJWT(userId, time.Minute)
+	if err != nil {
+		t.Fatalf("challenge creation failed")
+	}
+	if _, err := keyring.ValidateJWT(context.Background(), challenge); err == nil {
+		t.Fatalf("challenge token accepted as access token")
+	}
+	gotId, err := keyring.ValidateChallengeJWT(challenge)
+	if err != nil || gotId != userId {
+		t.Fatalf("challenge token rejected: %v", err)
+	}
+
+	access, err := keyring.MakeJWT(AccessToken{UserID: userId, ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("token creation failed")
+	}
+	if _, err := keyring.ValidateChallengeJWT(access); err == nil {
+		t.Fatalf("access token accepted as challenge token")
+	}
+}
+
+func TestKeyring_Denylist(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	sessionId := uuid.New()
+	keyring, err := NewKeyring("ed-1", makeTestEd25519Key(t, "ed-1"))
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+	denylist := NewMemoryDenylist()
+	keyring.UseDenylist(denylist)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Minute)
+	token, err := keyring.MakeJWT(AccessToken{ID: "jti-1", UserID: userId, SessionID: sessionId, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("token creation failed")
+	}
+	got, err := keyring.ValidateJWT(ctx, token)
+	if err != nil {
+		t.Fatalf("token rejected before being denied: %v", err)
+	}
+	if got.ID != "jti-1" || got.SessionID != sessionId {
+		t.Fatalf("unexpected claims in token: %+v", got)
+	}
+
+	if err := denylist.Deny(ctx, "jti-1", expiresAt); err != nil {
+		t.Fatalf("error denying token: %v", err)
+	}
+	if _, err := keyring.ValidateJWT(ctx, token); err == nil {
+		t.Fatalf("denied token was accepted")
+	}
+
+	other, _ := keyring.MakeJWT(AccessToken{ID: "jti-2", UserID: userId, ExpiresAt: expiresAt})
+	if _, err := keyring.ValidateJWT(ctx, other); err != nil {
+		t.Fatalf("token with a different id rejected: %v", err)
+	}
+}
+
+func TestMemoryDenylist_ForgetsExpiredEntries(t *testing.T) {
+	denylist := NewMemoryDenylist()
+	ctx := context.Background()
+
+	denylist.Deny(ctx, "expired", time.Now().Add(-time.Second))
+	if denied, _ := denylist.IsDenied(ctx, "expired"); denied {
+		t.Fatalf("entry for an expired token kept")
+	}
+	if len(denylist.entries) != 0 {
+		t.Fatalf("expected no entries, got %d", len(denylist.entries))
+	}
+}
+
+func TestKeyring_AccessAudienceRequired(t *testing.T) {
+	userId, _ := uuid.Parse("3f1c2e7a-9b5b-4c2a-8f6a-1a2b3c4d5e6f")
+	keyring, err := NewKeyring("ed-1", makeTestEd25519Key(t, "ed-1"))
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+
+	token, err := keyring.signJWT(jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Minute)),
+		Subject:   userId.String(),
+	})
+	if err != nil {
+		t.Fatalf("token creation failed")
+	}
+	if _, err := keyring.ValidateJWT(context.Background(), token); err == nil {
+		t.Fatalf("token without access audience accepted")
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Names of the password policy rules, reported back to clients when a
+// password is rejected.
+const (
+	RuleMinLength      = "min_length"
+	RuleMaxLength      = "max_length"
+	RuleCommonPassword = "common_password"
+	RuleSimilarToEmail = "similar_to_email"
+	RuleBreached       = "breached_password"
+)
+
+// commonPasswords are rejected even when no banned password file is
+// configured.
+var commonPasswords = []string{
+	"password", "password1", "password123", "passw0rd", "123456", "12345678",
+	"123456789", "1234567890", "qwerty", "qwerty123", "qwertyuiop", "abc123",
+	"111111", "000000", "iloveyou", "letmein", "welcome", "admin", "monkey",
+	"dragon", "football", "baseball", "sunshine", "princess", "trustno1",
+	"chirpy", "chirpy123",
+}
+
+// PasswordPolicy decides whether a password is acceptable for a new or
+// changed credential. Lengths are counted in characters.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+
+	banned   map[string]struct{}
+	breached *BreachedPasswords
+}
+
+func NewPasswordPolicy(minLength, maxLength int) *PasswordPolicy {
+	p := &PasswordPolicy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		banned:    make(map[string]struct{}),
+	}
+	for _, password := range commonPasswords {
+		p.banned[password] = struct{}{}
+	}
+	return p
+}
+
+// LoadBannedPasswords adds the passwords in the file at path, one per line,
+// to the ones the policy rejects. Matching ignores case.
+func (p *PasswordPolicy) LoadBannedPasswords(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		password := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if password != "" {
+			p.banned[password] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
+
+// UseBreachedPasswords makes the policy reject passwords found in b.
+func (p *PasswordPolicy) UseBreachedPasswords(b *BreachedPasswords) {
+	p.breached = b
+}
+
+// Check returns the names of the rules password breaks, or none if it is
+// acceptable. email is the address of the account the password is for.
+func (p *PasswordPolicy) Check(password, email string) ([]string, error) {
+	failed := []string{}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		failed = append(failed, RuleMinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		failed = append(failed, RuleMaxLength)
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := p.banned[lower]; ok {
+		failed = append(failed, RuleCommonPassword)
+	}
+	if similarToEmail(lower, strings.ToLower(email)) {
+		failed = append(failed, RuleSimilarToEmail)
+	}
+
+	if p.breached != nil && password != "" {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			failed = append(failed, RuleBreached)
+		}
+	}
+
+	return failed, nil
+}
+
+// similarToEmail reports whether the password is built from the email
+// address: the whole address, or its local part or domain name.
+func similarToEmail(password, email string) bool {
+	if password == "" || email == "" {
+		return false
+	}
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	local, domain, _ := strings.Cut(email, "@")
+	domainName, _, _ := strings.Cut(domain, ".")
+	for _, part := range []string{local, domainName} {
+		if len(part) < 3 {
+			continue
+		}
+		if strings.Contains(password, part) || strings.Contains(part, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// BreachedPasswords looks passwords up in a local copy of a breached
+// password corpus laid out like the Pwned Passwords range API: the SHA-1
+// hashes are split by their first five hex characters into files named
+// PREFIX.txt, each holding one SUFFIX:COUNT line per hash. Only the file for
+// the password's prefix is read.
+type BreachedPasswords struct {
+	dir string
+}
+
+func NewBreachedPasswords(dir string) (*BreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New("breached password path is not a directory")
+	}
+	return &BreachedPasswords{dir: dir}, nil
+}
+
+const breachedPrefixLength = 5
+
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	f, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineSuffix, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(lineSuffix), suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := NewPasswordPolicy(10, 64)
+
+	cases := []struct {
+		password string
+		failed   []string
+	}{
+		{"", []string{RuleMinLength}},
+		{"short", []string{RuleMinLength}},
+		{"Password123", []string{RuleCommonPassword}},
+		{"alice.smith2024", []string{RuleSimilarToEmail}},
+		{"my-example-pass", []string{RuleSimilarToEmail}},
+		{strings.Repeat("x", 65), []string{RuleMaxLength}},
+		{"correct horse battery staple", []string{}},
+	}
+	for _, c := range cases {
+		failed, err := policy.Check(c.password, "alice.smith@example.com")
+		if err != nil {
+			t.Fatalf("error checking %q: %v", c.password, err)
+		}
+		if !slices.Equal(failed, c.failed) {
+			t.Fatalf("password %q failed %v, expected %v", c.password, failed, c.failed)
+		}
+	}
+}
+
+func TestPasswordPolicy_BannedPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	err := os.WriteFile(path, []byte("Tr0ub4dor&3\n\n  springtime2024  \n"), 0600)
+	if err != nil {
+		t.Fatalf("error writing banned password file: %v", err)
+	}
+
+	policy := NewPasswordPolicy(8, 0)
+	if err := policy.LoadBannedPasswords(path); err != nil {
+		t.Fatalf("error loading banned passwords: %v", err)
+	}
+
+	for _, password := range []string{"tr0ub4dor&3", "SPRINGTIME2024"} {
+		failed, _ := policy.Check(password, "bob@example.com")
+		if !slices.Equal(failed, []string{RuleCommonPassword}) {
+			t.Fatalf("banned password %q failed %v", password, failed)
+		}
+	}
+}
+
+func TestBreachedPasswords_Contains(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("hunter2hunter2"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	ranges := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n" + hash[5:] + ":42\n"
+	err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(ranges), 0600)
+	if err != nil {
+		t.Fatalf("error writing range file: %v", err)
+	}
+
+	breached, err := NewBreachedPasswords(dir)
+	if err != nil {
+		t.Fatalf("error opening breached passwords: %v", err)
+	}
+	policy := NewPasswordPolicy(8, 0)
+	policy.UseBreachedPasswords(breached)
+
+	failed, err := policy.Check("hunter2hunter2", "bob@example.com")
+	if err != nil {
+		t.Fatalf("error checking password: %v", err)
+	}
+	if !slices.Equal(failed, []string{RuleBreached}) {
+		t.Fatalf("breached password failed %v", failed)
+	}
+
+	failed, err = policy.Check("not in any breach", "bob@example.com")
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("password with no range file failed %v: %v", failed, err)
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	hasher := NewPasswordHasher(testArgon2Params)
+	hash, err := hasher.Hash("hunter2")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected hash format: %s", hash)
+	}
+
+	needsRehash, err := hasher.Verify("hunter2", hash)
+	if err != nil {
+		t.Fatalf("correct password rejected: %v", err)
+	}
+	if needsRehash {
+		t.Fatalf("hash with current parameters flagged for rehash")
+	}
+
+	if _, err := hasher.Verify("hunter3", hash); err != ErrPasswordMismatch {
+		t.Fatalf("wrong password not rejected: %v", err)
+	}
+}
+
+func TestPasswordHasher_RehashOnParamChange(t *testing.T) {
+	hash, err := NewPasswordHasher(testArgon2Params).Hash("hunter2")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	stronger := testArgon2Params
+	stronger.Iterations = 2
+	needsRehash, err := NewPasswordHasher(stronger).Verify("hunter2", hash)
+	if err != nil {
+		t.Fatalf("hash with old parameters rejected: %v", err)
+	}
+	if !needsRehash {
+		t.Fatalf("hash with old parameters not flagged for rehash")
+	}
+}
+
+func TestPasswordHasher_Bcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("error creating bcrypt hash: %v", err)
+	}
+
+	hasher := NewPasswordHasher(testArgon2Params)
+	needsRehash, err := hasher.Verify("hunter2", string(hash))
+	if err != nil {
+		t.Fatalf("bcrypt hash rejected: %v", err)
+	}
+	if !needsRehash {
+		t.Fatalf("bcrypt hash not flagged for rehash")
+	}
+
+	if _, err := hasher.Verify("hunter3", string(hash)); err != ErrPasswordMismatch {
+		t.Fatalf("wrong password not rejected: %v", err)
+	}
+}
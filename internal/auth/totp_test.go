@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B, truncated to 6 digits.
+func TestTOTPCode_RFC6238(t *testing.T) {
+	secret := base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, c := range cases {
+		code, err := TOTPCode(secret, time.Unix(c.unix, 0))
+		if err != nil {
+			t.Fatalf("error generating code: %v", err)
+		}
+		if code != c.code {
+			t.Fatalf("wrong code at %d: got %s, want %s", c.unix, code, c.code)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	code, _ := TOTPCode(secret, now)
+	step, ok := VerifyTOTP(secret, code, now)
+	if !ok {
+		t.Fatalf("current code rejected")
+	}
+	if step != now.Unix()/totpPeriod {
+		t.Fatalf("wrong step returned: %d", step)
+	}
+
+	previous, _ := TOTPCode(secret, now.Add(-totpPeriod*time.Second))
+	if _, ok := VerifyTOTP(secret, previous, now); !ok {
+		t.Fatalf("code from previous step rejected")
+	}
+
+	stale, _ := TOTPCode(secret, now.Add(-5*totpPeriod*time.Second))
+	if _, ok := VerifyTOTP(secret, stale, now); ok {
+		t.Fatalf("stale code accepted")
+	}
+
+	if _, ok := VerifyTOTP(secret, "12345", now); ok {
+		t.Fatalf("short code accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "Chirpy", "user@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?") {
+		t.Fatalf("unexpected uri: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Chirpy") {
+		t.Fatalf("uri missing parameters: %s", uri)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("error generating recovery codes: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("unexpected recovery code format: %s", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate recovery code: %s", code)
+		}
+		seen[code] = true
+	}
+
+	if NormalizeRecoveryCode(" ABCDE-fghij ") != "abcdefghij" {
+		t.Fatalf("recovery code not normalized")
+	}
+}
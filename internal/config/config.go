@@ -9,9 +9,80 @@ import (
 const CONFIG_FILE_NAME = ".chirpyconfig.json"
 
 type Config struct {
-	DB_URL     string
-	JWT_SECRET string
-	POLKA_KEY  string
+	DB_URL         string
+	JWT_SECRET     string
+	POLKA_KEY      string
+	TOKEN_HASH_KEY string
+
+	// PLATFORM is "dev" on development machines. Destructive admin
+	// endpoints such as reset refuse to run anywhere else.
+	PLATFORM string
+
+	// JWT_SIGNING_KEYS lists the asymmetric keys used for access tokens and
+	// JWT_ACTIVE_KEY_ID selects the one new tokens are signed with. When no
+	// active key is set tokens are signed with JWT_SECRET as before.
+	JWT_SIGNING_KEYS  []JWTKeyConfig
+	JWT_ACTIVE_KEY_ID string
+
+	// DENYLIST selects where revoked access token ids are kept: "db", the
+	// default, or "memory" for a single server. The memory denylist is
+	// forgotten on restart.
+	DENYLIST string
+
+	// ARGON2_* set the cost of new password hashes. Unset values use the
+	// defaults in auth.DefaultArgon2Params. Raising them upgrades existing
+	// hashes the next time each user logs in.
+	ARGON2_MEMORY_KIB  uint32
+	ARGON2_ITERATIONS  uint32
+	ARGON2_PARALLELISM uint8
+
+	// PASSWORD_* configure the policy new passwords must meet. The lengths
+	// default to 12 and 128 characters. PASSWORD_BANNED_FILE lists extra
+	// passwords to reject, one per line. PASSWORD_BREACHED_DIR holds SHA-1
+	// range files in the Pwned Passwords format, named PREFIX.txt.
+	PASSWORD_MIN_LENGTH   int
+	PASSWORD_MAX_LENGTH   int
+	PASSWORD_BANNED_FILE  string
+	PASSWORD_BREACHED_DIR string
+
+	// APP_BASE_URL is used to build links in emails sent to users.
+	APP_BASE_URL string
+
+	// MAILER selects how email is delivered: "smtp", or "log" to write
+	// messages to MAIL_LOG_FILE (or the application log) for development.
+	MAILER        string
+	MAIL_FROM     string
+	MAIL_LOG_FILE string
+	SMTP_HOST     string
+	SMTP_PORT     int
+	SMTP_USERNAME string
+	SMTP_PASSWORD string
+
+	// OIDC_* configure single sign-on through an OpenID Connect identity
+	// provider. It is disabled when OIDC_ISSUER is empty. OIDC_REDIRECT_URL
+	// must point at /api/oidc/callback.
+	OIDC_ISSUER        string
+	OIDC_CLIENT_ID     string
+	OIDC_CLIENT_SECRET string
+	OIDC_REDIRECT_URL  string
+
+	// ACCOUNT_DELETION_GRACE_DAYS is how many days a deleted account can
+	// still be recovered by logging in before it is removed for good.
+	// Defaults to 30.
+	ACCOUNT_DELETION_GRACE_DAYS int
+
+	// CHIRP_EDIT_WINDOW_MINUTES is how long after posting a chirp its author
+	// can edit it. Defaults to 15.
+	CHIRP_EDIT_WINDOW_MINUTES int
+
+	// REQUIRE_VERIFIED_EMAIL stops users from posting chirps until they
+	// have verified their email address.
+	REQUIRE_VERIFIED_EMAIL bool
+}
+
+type JWTKeyConfig struct {
+	KID      string
+	KEY_FILE string
 }
 
 func Read() (Config, error) {
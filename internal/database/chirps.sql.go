@@ -7,23 +7,44 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasRepliesOrQuotes = `-- name: ChirpHasRepliesOrQuotes :one
+SELECT EXISTS (
+    SELECT 1
+    FROM chirps
+    WHERE parent_chirp_id = $1 OR quote_of_id = $1
+)
+`
+
+func (q *Queries) ChirpHasRepliesOrQuotes(ctx context.Context, parentChirpID uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasRepliesOrQuotes, parentChirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, root_chirp_id, rechirp_of_id, quote_of_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, root_chirp_id, deleted_at, rechirp_of_id, quote_of_id
 `
 
 type CreateChirpParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.UUID
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RootChirpID   uuid.NullUUID
+	RechirpOfID   uuid.NullUUID
+	QuoteOfID     uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
@@ -33,6 +54,10 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		arg.UpdatedAt,
 		arg.Body,
 		arg.UserID,
+		arg.ParentChirpID,
+		arg.RootChirpID,
+		arg.RechirpOfID,
+		arg.QuoteOfID,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -41,6 +66,12 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.RootChirpID,
+		&i.DeletedAt,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
 	)
 	return i, err
 }
@@ -56,10 +87,39 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE rechirp_of_id = $1 AND user_id = $2
+`
+
+type DeleteRechirpParams struct {
+	RechirpOfID uuid.NullUUID
+	UserID      uuid.UUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.RechirpOfID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of_id = $1
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, rechirpOfID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOf, rechirpOfID)
+	return err
+}
+
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id
 FROM chirps
-WHERE id = $1
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL AND users.deletion_requested_at IS NULL
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -71,18 +131,269 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.RootChirpID,
+		&i.DeletedAt,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+const getChirpCounts = `-- name: GetChirpCounts :many
+SELECT chirps.id,
+    (
+        SELECT COUNT(*)
+        FROM chirp_likes
+        JOIN users ON users.id = chirp_likes.user_id
+        WHERE chirp_likes.chirp_id = chirps.id AND users.deletion_requested_at IS NULL
+    ) AS like_count,
+    EXISTS (
+        SELECT 1
+        FROM chirp_likes
+        WHERE chirp_likes.chirp_id = chirps.id AND chirp_likes.user_id = $1
+    ) AS liked_by_me,
+    (
+        SELECT COUNT(*)
+        FROM chirps AS rechirps
+        JOIN users ON users.id = rechirps.user_id
+        WHERE rechirps.rechirp_of_id = chirps.id AND users.deletion_requested_at IS NULL
+    ) AS rechirp_count,
+    (
+        SELECT COUNT(*)
+        FROM chirps AS quotes
+        JOIN users ON users.id = quotes.user_id
+        WHERE quotes.quote_of_id = chirps.id AND quotes.deleted_at IS NULL AND users.deletion_requested_at IS NULL
+    ) AS quote_count
 FROM chirps
-ORDER BY created_at DESC
+WHERE chirps.id = ANY($2::uuid[])
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpCountsParams struct {
+	ViewerID uuid.UUID
+	Ids      []uuid.UUID
+}
+
+type GetChirpCountsRow struct {
+	ID           uuid.UUID
+	LikeCount    int64
+	LikedByMe    bool
+	RechirpCount int64
+	QuoteCount   int64
+}
+
+func (q *Queries) GetChirpCounts(ctx context.Context, arg GetChirpCountsParams) ([]GetChirpCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpCounts, arg.ViewerID, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpCountsRow
+	for rows.Next() {
+		var i GetChirpCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LikeCount,
+			&i.LikedByMe,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id, 1 AS depth
+    FROM chirps
+    WHERE chirps.parent_chirp_id = ANY($1::uuid[])
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.parent_chirp_id = thread.id
+    WHERE thread.depth < $2::int
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.parent_chirp_id, thread.root_chirp_id, thread.quote_of_id,
+    COALESCE(thread.deleted_at, users.deletion_requested_at) AS deleted_at,
+    EXISTS (
+        SELECT 1
+        FROM chirps AS replies
+        WHERE replies.parent_chirp_id = thread.id
+    ) AS has_replies
+FROM thread
+JOIN users ON users.id = thread.user_id
+ORDER BY thread.depth, thread.created_at, thread.id
+LIMIT $3
+`
+
+type GetChirpDescendantsParams struct {
+	ParentIds []uuid.UUID
+	MaxDepth  int32
+	Limit     int32
+}
+
+type GetChirpDescendantsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RootChirpID   uuid.NullUUID
+	QuoteOfID     uuid.NullUUID
+	DeletedAt     sql.NullTime
+	HasReplies    bool
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, pq.Array(arg.ParentIds), arg.MaxDepth, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.HasReplies,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, root_chirp_id, deleted_at, rechirp_of_id, quote_of_id
+FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.RootChirpID,
+		&i.DeletedAt,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+	)
+	return i, err
+}
+
+const getChirpWithAncestors = `-- name: GetChirpWithAncestors :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = $1
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.id = thread.parent_chirp_id
+    WHERE thread.depth < $2::int
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.parent_chirp_id, thread.root_chirp_id, thread.quote_of_id,
+    COALESCE(thread.deleted_at, users.deletion_requested_at) AS deleted_at,
+    thread.depth
+FROM thread
+JOIN users ON users.id = thread.user_id
+ORDER BY thread.depth DESC
+`
+
+type GetChirpWithAncestorsParams struct {
+	ID       uuid.UUID
+	MaxDepth int32
+}
+
+type GetChirpWithAncestorsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RootChirpID   uuid.NullUUID
+	QuoteOfID     uuid.NullUUID
+	DeletedAt     sql.NullTime
+	Depth         int32
+}
+
+func (q *Queries) GetChirpWithAncestors(ctx context.Context, arg GetChirpWithAncestorsParams) ([]GetChirpWithAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpWithAncestors, arg.ID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpWithAncestorsRow
+	for rows.Next() {
+		var i GetChirpWithAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, root_chirp_id, deleted_at, rechirp_of_id, quote_of_id
+FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +407,12 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.DeletedAt,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
 		); err != nil {
 			return nil, err
 		}
@@ -110,15 +427,226 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id
+const listChirpReplies = `-- name: ListChirpReplies :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.quote_of_id,
+    COALESCE(chirps.deleted_at, users.deletion_requested_at) AS deleted_at,
+    EXISTS (
+        SELECT 1
+        FROM chirps AS replies
+        WHERE replies.parent_chirp_id = chirps.id
+    ) AS has_replies
 FROM chirps
-WHERE user_id = $1
-ORDER BY created_at DESC
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.parent_chirp_id = $1
+  AND ($2::timestamp IS NULL OR (chirps.created_at, chirps.id) > ($2, $3::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type ListChirpRepliesParams struct {
+	ParentChirpID   uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+type ListChirpRepliesRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RootChirpID   uuid.NullUUID
+	QuoteOfID     uuid.NullUUID
+	DeletedAt     sql.NullTime
+	HasReplies    bool
+}
+
+func (q *Queries) ListChirpReplies(ctx context.Context, arg ListChirpRepliesParams) ([]ListChirpRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpReplies,
+		arg.ParentChirpID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpRepliesRow
+	for rows.Next() {
+		var i ListChirpRepliesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.HasReplies,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND ($1::uuid IS NULL OR chirps.user_id = $1)
+  AND ($2::timestamp IS NULL OR (chirps.created_at, chirps.id) > ($2, $3::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ListChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.DeletedAt,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND ($1::uuid IS NULL OR chirps.user_id = $1)
+  AND ($2::timestamp IS NULL OR (chirps.created_at, chirps.id) < ($2, $3::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.DeletedAt,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeline = `-- name: ListTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE follows.follower_id = $1
+  AND users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND ($2::timestamp IS NULL OR (chirps.created_at, chirps.id) < ($2, $3::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type ListTimelineParams struct {
+	FollowerID      uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+func (q *Queries) ListTimeline(ctx context.Context, arg ListTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeline,
+		arg.FollowerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +660,12 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.RootChirpID,
+			&i.DeletedAt,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
 		); err != nil {
 			return nil, err
 		}
@@ -154,3 +688,120 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.deleted_at, chirps.rechirp_of_id, chirps.quote_of_id, ts_rank(chirps.search_vector, query) AS rank
+FROM chirps
+JOIN users ON users.id = chirps.user_id,
+    to_tsquery('english', $1) AS query
+WHERE users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND chirps.search_vector @@ query
+  AND ($2::uuid IS NULL OR chirps.user_id = $2)
+  AND ($3::real IS NULL OR (ts_rank(chirps.search_vector, query), chirps.id) < ($3, $4::uuid))
+ORDER BY rank DESC, chirps.id DESC
+LIMIT $5
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	AuthorID   uuid.NullUUID
+	CursorRank sql.NullFloat64
+	CursorID   uuid.UUID
+	Limit      int32
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.CursorRank,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.RootChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.RechirpOfID,
+			&i.Chirp.QuoteOfID,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', updated_at = $2, deleted_at = $2
+WHERE id = $1
+`
+
+type TombstoneChirpParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) TombstoneChirp(ctx context.Context, arg TombstoneChirpParams) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, root_chirp_id, deleted_at, rechirp_of_id, quote_of_id
+`
+
+type UpdateChirpBodyParams struct {
+	ID        uuid.UUID
+	Body      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body, arg.UpdatedAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.RootChirpID,
+		&i.DeletedAt,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+	)
+	return i, err
+}
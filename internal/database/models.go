@@ -11,28 +11,149 @@ import (
 	"github.com/google/uuid"
 )
 
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	HashtagID uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
 type Chirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	SearchVector  interface{}
+	ParentChirpID uuid.NullUUID
+	RootChirpID   uuid.NullUUID
+	DeletedAt     sql.NullTime
+	RechirpOfID   uuid.NullUUID
+	QuoteOfID     uuid.NullUUID
+}
+
+type DeniedAccessToken struct {
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
 	ID        uuid.UUID
+	Tag       string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type LoginFailure struct {
+	Email            string
+	FailedLoginCount int32
+	LockedUntil      sql.NullTime
+	LastFailedAt     time.Time
+}
+
+type OidcLoginState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	TokenHash            string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	UserID               uuid.UUID
+	ExpiresAt            sql.NullTime
+	RevokedAt            sql.NullTime
+	FamilyID             uuid.UUID
+	RotatedAt            sql.NullTime
+	SessionStartedAt     time.Time
+	UserAgent            string
+	IpAddress            string
+	AccessTokenID        string
+	AccessTokenExpiresAt sql.NullTime
+}
+
+type UserIdentity struct {
+	Issuer    string
+	Subject   string
 	UserID    uuid.UUID
-	ExpiresAt sql.NullTime
-	RevokedAt sql.NullTime
+	CreatedAt time.Time
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Email               string
+	HashedPassword      string
+	IsChirpyRed         sql.NullBool
+	EmailVerifiedAt     sql.NullTime
+	TotpSecret          sql.NullString
+	TotpEnabledAt       sql.NullTime
+	TotpLastUsedStep    sql.NullInt64
+	FailedLoginCount    int32
+	LockedUntil         sql.NullTime
+	Role                string
+	SuspendedAt         sql.NullTime
+	DeletionRequestedAt sql.NullTime
+	Handle              string
+	DisplayName         string
+	Bio                 string
+	LastFailedLoginAt   sql.NullTime
 }
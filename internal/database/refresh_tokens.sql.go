@@ -14,77 +14,229 @@ import (
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	ExpiresAt sql.NullTime
-	RevokedAt sql.NullTime
+	TokenHash            string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	UserID               uuid.UUID
+	ExpiresAt            sql.NullTime
+	RevokedAt            sql.NullTime
+	FamilyID             uuid.UUID
+	SessionStartedAt     time.Time
+	UserAgent            string
+	IpAddress            string
+	AccessTokenID        string
+	AccessTokenExpiresAt sql.NullTime
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
 	row := q.db.QueryRowContext(ctx, createRefreshToken,
-		arg.Token,
+		arg.TokenHash,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.ExpiresAt,
 		arg.RevokedAt,
+		arg.FamilyID,
+		arg.SessionStartedAt,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.AccessTokenID,
+		arg.AccessTokenExpiresAt,
 	)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.SessionStartedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessTokenExpiresAt,
 	)
 	return i, err
 }
 
-const getRefreshTokenByToken = `-- name: GetRefreshTokenByToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+const getActiveRefreshTokensByUserID = `-- name: GetActiveRefreshTokensByUserID :many
+SELECT token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at
 FROM refresh_tokens
-WHERE token = $1
+WHERE user_id = $1 AND revoked_at IS NULL AND rotated_at IS NULL AND expires_at > $2
+ORDER BY created_at DESC
 `
 
-func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getRefreshTokenByToken, token)
-	var i RefreshToken
-	err := row.Scan(
-		&i.Token,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.UserID,
-		&i.ExpiresAt,
-		&i.RevokedAt,
-	)
-	return i, err
+type GetActiveRefreshTokensByUserIDParams struct {
+	UserID    uuid.UUID
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) GetActiveRefreshTokensByUserID(ctx context.Context, arg GetActiveRefreshTokensByUserIDParams) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveRefreshTokensByUserID, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.TokenHash,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.FamilyID,
+			&i.RotatedAt,
+			&i.SessionStartedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.AccessTokenID,
+			&i.AccessTokenExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLiveAccessTokensByFamilyID = `-- name: GetLiveAccessTokensByFamilyID :many
+SELECT token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at
+FROM refresh_tokens
+WHERE family_id = $1 AND access_token_id <> '' AND access_token_expires_at > $2
+`
+
+type GetLiveAccessTokensByFamilyIDParams struct {
+	FamilyID             uuid.UUID
+	AccessTokenExpiresAt sql.NullTime
+}
+
+func (q *Queries) GetLiveAccessTokensByFamilyID(ctx context.Context, arg GetLiveAccessTokensByFamilyIDParams) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getLiveAccessTokensByFamilyID, arg.FamilyID, arg.AccessTokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.TokenHash,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.FamilyID,
+			&i.RotatedAt,
+			&i.SessionStartedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.AccessTokenID,
+			&i.AccessTokenExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOtherLiveAccessTokensByUserID = `-- name: GetOtherLiveAccessTokensByUserID :many
+SELECT token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at
+FROM refresh_tokens
+WHERE user_id = $1 AND family_id <> $2 AND access_token_id <> '' AND access_token_expires_at > $3
+`
+
+type GetOtherLiveAccessTokensByUserIDParams struct {
+	UserID               uuid.UUID
+	FamilyID             uuid.UUID
+	AccessTokenExpiresAt sql.NullTime
 }
 
-const getRefreshTokenByUserID = `-- name: GetRefreshTokenByUserID :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+func (q *Queries) GetOtherLiveAccessTokensByUserID(ctx context.Context, arg GetOtherLiveAccessTokensByUserIDParams) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getOtherLiveAccessTokensByUserID, arg.UserID, arg.FamilyID, arg.AccessTokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.TokenHash,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.FamilyID,
+			&i.RotatedAt,
+			&i.SessionStartedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.AccessTokenID,
+			&i.AccessTokenExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
+SELECT token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at
 FROM refresh_tokens
-WHERE user_id = $1
+WHERE token_hash = $1
 `
 
-func (q *Queries) GetRefreshTokenByUserID(ctx context.Context, userID uuid.UUID) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getRefreshTokenByUserID, userID)
+func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenByHash, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.SessionStartedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessTokenExpiresAt,
 	)
 	return i, err
 }
@@ -98,19 +250,136 @@ func (q *Queries) ResetRefreshTokens(ctx context.Context) error {
 	return err
 }
 
+const revokeOtherRefreshTokensByUserID = `-- name: RevokeOtherRefreshTokensByUserID :exec
+UPDATE refresh_tokens
+SET updated_at = $3, revoked_at = $4
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeOtherRefreshTokensByUserIDParams struct {
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	UpdatedAt time.Time
+	RevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeOtherRefreshTokensByUserID(ctx context.Context, arg RevokeOtherRefreshTokensByUserIDParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherRefreshTokensByUserID,
+		arg.UserID,
+		arg.FamilyID,
+		arg.UpdatedAt,
+		arg.RevokedAt,
+	)
+	return err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = $2, revoked_at = $3
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokenFamilyParams struct {
+	FamilyID  uuid.UUID
+	UpdatedAt time.Time
+	RevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, arg.FamilyID, arg.UpdatedAt, arg.RevokedAt)
+	return err
+}
+
+const revokeRefreshTokenFamilyForUser = `-- name: RevokeRefreshTokenFamilyForUser :execrows
+UPDATE refresh_tokens
+SET updated_at = $3, revoked_at = $4
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokenFamilyForUserParams struct {
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+	RevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeRefreshTokenFamilyForUser(ctx context.Context, arg RevokeRefreshTokenFamilyForUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamilyForUser,
+		arg.FamilyID,
+		arg.UserID,
+		arg.UpdatedAt,
+		arg.RevokedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeRefreshTokensByUserID = `-- name: RevokeRefreshTokensByUserID :exec
+UPDATE refresh_tokens
+SET updated_at = $2, revoked_at = $3
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokensByUserIDParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+	RevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeRefreshTokensByUserID(ctx context.Context, arg RevokeRefreshTokensByUserIDParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokensByUserID, arg.UserID, arg.UpdatedAt, arg.RevokedAt)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET updated_at = $2, rotated_at = $3
+WHERE token_hash = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+RETURNING token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at
+`
+
+type RotateRefreshTokenParams struct {
+	TokenHash string
+	UpdatedAt time.Time
+	RotatedAt sql.NullTime
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, arg.TokenHash, arg.UpdatedAt, arg.RotatedAt)
+	var i RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.SessionStartedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessTokenExpiresAt,
+	)
+	return i, err
+}
+
 const setRefreshTokenRevoked = `-- name: SetRefreshTokenRevoked :exec
 UPDATE refresh_tokens
 SET updated_at = $2, revoked_at = $3
-WHERE token = $1
+WHERE token_hash = $1
 `
 
 type SetRefreshTokenRevokedParams struct {
-	Token     string
+	TokenHash string
 	UpdatedAt time.Time
 	RevokedAt sql.NullTime
 }
 
 func (q *Queries) SetRefreshTokenRevoked(ctx context.Context, arg SetRefreshTokenRevokedParams) error {
-	_, err := q.db.ExecContext(ctx, setRefreshTokenRevoked, arg.Token, arg.UpdatedAt, arg.RevokedAt)
+	_, err := q.db.ExecContext(ctx, setRefreshTokenRevoked, arg.TokenHash, arg.UpdatedAt, arg.RevokedAt)
 	return err
 }
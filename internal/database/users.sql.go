@@ -7,15 +7,33 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :exec
+UPDATE users
+SET updated_at = $2, deletion_requested_at = NULL
+WHERE id = $1
+`
+
+type CancelUserDeletionParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, arg CancelUserDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, cancelUserDeletion, arg.ID, arg.UpdatedAt)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
 `
 
 type CreateUserParams struct {
@@ -24,6 +42,7 @@ type CreateUserParams struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -33,6 +52,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.UpdatedAt,
 		arg.Email,
 		arg.HashedPassword,
+		arg.Handle,
 	)
 	var i User
 	err := row.Scan(
@@ -42,12 +62,71 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
 	)
 	return i, err
 }
 
+const deleteUsersPendingDeletion = `-- name: DeleteUsersPendingDeletion :execrows
+DELETE FROM users
+WHERE deletion_requested_at <= $1
+`
+
+func (q *Queries) DeleteUsersPendingDeletion(ctx context.Context, deletionRequestedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUsersPendingDeletion, deletionRequestedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET updated_at = $2, totp_secret = NULL, totp_enabled_at = NULL, totp_last_used_step = NULL
+WHERE id = $1
+`
+
+type DisableUserTOTPParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, arg DisableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE users
+SET updated_at = $2, totp_enabled_at = $3
+WHERE id = $1
+`
+
+type EnableUserTOTPParams struct {
+	ID            uuid.UUID
+	UpdatedAt     time.Time
+	TotpEnabledAt sql.NullTime
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, arg.ID, arg.UpdatedAt, arg.TotpEnabledAt)
+	return err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
 FROM users
 WHERE email = $1
 `
@@ -62,12 +141,58 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
+FROM users
+WHERE handle = $1 AND deletion_requested_at IS NULL
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
 FROM users
 WHERE ID = $1
 `
@@ -82,10 +207,130 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
 	)
 	return i, err
 }
 
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
+FROM users
+WHERE id = ANY($1::uuid[]) AND deletion_requested_at IS NULL
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastUsedStep,
+			&i.FailedLoginCount,
+			&i.LockedUntil,
+			&i.Role,
+			&i.SuspendedAt,
+			&i.DeletionRequestedAt,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.LastFailedLoginAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUser = `-- name: LockUser :exec
+UPDATE users
+SET locked_until = $2
+WHERE id = $1
+`
+
+type LockUserParams struct {
+	ID          uuid.UUID
+	LockedUntil sql.NullTime
+}
+
+func (q *Queries) LockUser(ctx context.Context, arg LockUserParams) error {
+	_, err := q.db.ExecContext(ctx, lockUser, arg.ID, arg.LockedUntil)
+	return err
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_count = CASE
+        WHEN last_failed_login_at >= $1::timestamp OR locked_until >= $1::timestamp
+        THEN failed_login_count + 1
+        ELSE 1
+    END,
+    last_failed_login_at = $2::timestamp
+WHERE id = $3
+RETURNING failed_login_count
+`
+
+type RecordFailedLoginParams struct {
+	WindowStart time.Time
+	FailedAt    time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFailedLogin, arg.WindowStart, arg.FailedAt, arg.ID)
+	var failed_login_count int32
+	err := row.Scan(&failed_login_count)
+	return failed_login_count, err
+}
+
+const requestUserDeletion = `-- name: RequestUserDeletion :exec
+UPDATE users
+SET updated_at = $2, deletion_requested_at = $3
+WHERE id = $1
+`
+
+type RequestUserDeletionParams struct {
+	ID                  uuid.UUID
+	UpdatedAt           time.Time
+	DeletionRequestedAt sql.NullTime
+}
+
+func (q *Queries) RequestUserDeletion(ctx context.Context, arg RequestUserDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, requestUserDeletion, arg.ID, arg.UpdatedAt, arg.DeletionRequestedAt)
+	return err
+}
+
 const resetUsers = `-- name: ResetUsers :exec
 DELETE FROM users
 `
@@ -95,11 +340,83 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET updated_at = $2, role = $3
+WHERE id = $1
+`
+
+type SetUserRoleParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+	Role      string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.ID, arg.UpdatedAt, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserSuspended = `-- name: SetUserSuspended :execrows
+UPDATE users
+SET updated_at = $2, suspended_at = $3
+WHERE id = $1
+`
+
+type SetUserSuspendedParams struct {
+	ID          uuid.UUID
+	UpdatedAt   time.Time
+	SuspendedAt sql.NullTime
+}
+
+func (q *Queries) SetUserSuspended(ctx context.Context, arg SetUserSuspendedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserSuspended, arg.ID, arg.UpdatedAt, arg.SuspendedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET updated_at = $2, totp_secret = $3, totp_enabled_at = NULL, totp_last_used_step = NULL
+WHERE id = $1
+`
+
+type SetUserTOTPSecretParams struct {
+	ID         uuid.UUID
+	UpdatedAt  time.Time
+	TotpSecret sql.NullString
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.ID, arg.UpdatedAt, arg.TotpSecret)
+	return err
+}
+
+const unlockUser = `-- name: UnlockUser :execrows
+UPDATE users
+SET failed_login_count = 0, locked_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) UnlockUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unlockUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET updated_at = $2, email = $3, hashed_password = $4
+SET updated_at = $2, email = $3, hashed_password = $4,
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
 `
 
 type UpdateUserParams struct {
@@ -124,6 +441,125 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+SET updated_at = $2, email = $3,
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
+`
+
+type UpdateUserEmailParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+	Email     string
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserEmail, arg.ID, arg.UpdatedAt, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET updated_at = $2, hashed_password = $3
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	UpdatedAt      time.Time
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.UpdatedAt, arg.HashedPassword)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = $2, handle = $3, display_name = $4, bio = $5
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	UpdatedAt   time.Time
+	Handle      string
+	DisplayName string
+	Bio         string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.UpdatedAt,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
 	)
 	return i, err
 }
@@ -132,7 +568,7 @@ const upgradeUser = `-- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = true
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, failed_login_count, locked_until, role, suspended_at, deletion_requested_at, handle, display_name, bio, last_failed_login_at
 `
 
 func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -145,6 +581,64 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.FailedLoginCount,
+		&i.LockedUntil,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.DeletionRequestedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.LastFailedLoginAt,
 	)
 	return i, err
 }
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_used_step = $2
+WHERE id = $1 AND (totp_last_used_step IS NULL OR totp_last_used_step < $2)
+`
+
+type UseUserTOTPStepParams struct {
+	ID               uuid.UUID
+	TotpLastUsedStep sql.NullInt64
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.ID, arg.TotpLastUsedStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :execrows
+UPDATE users
+SET updated_at = $2, email_verified_at = $3
+WHERE id = $1 AND email = $4
+`
+
+type VerifyUserEmailParams struct {
+	ID              uuid.UUID
+	UpdatedAt       time.Time
+	EmailVerifiedAt sql.NullTime
+	Email           string
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, verifyUserEmail,
+		arg.ID,
+		arg.UpdatedAt,
+		arg.EmailVerifiedAt,
+		arg.Email,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,122 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/crisp-coder/chirpy/internal/oidc"
+	"github.com/crisp-coder/chirpy/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func setupProvider(t *testing.T) (*oidctest.IdP, *oidc.Provider) {
+	t.Helper()
+	idp, err := oidctest.NewIdP("chirpy", "client-secret")
+	if err != nil {
+		t.Fatalf("error starting mock idp: %v", err)
+	}
+	t.Cleanup(idp.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "chirpy",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost:8080/api/oidc/callback",
+	}, nil)
+	return idp, provider
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	idp, provider := setupProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.RandomString()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("error building auth url: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	if scope := u.Query().Get("scope"); scope != "openid email" {
+		t.Fatalf("unexpected scope: %q", scope)
+	}
+
+	code, state, err := idp.Authorize(authURL, oidctest.User{
+		Subject:       "user-1",
+		Email:         "user@example.com",
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("mock idp rejected authorization: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state not passed through: %q", state)
+	}
+
+	claims, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("error exchanging code: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestProvider_RejectsWrongVerifier(t *testing.T) {
+	idp, provider := setupProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.RandomString()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("error building auth url: %v", err)
+	}
+	code, _, err := idp.Authorize(authURL, oidctest.User{Subject: "user-1", Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("mock idp rejected authorization: %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, code, "wrong-verifier", "nonce-1"); err == nil {
+		t.Fatalf("code exchanged with the wrong pkce verifier")
+	}
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	idp, provider := setupProvider(t)
+	ctx := context.Background()
+
+	valid := jwt.MapClaims{
+		"iss":   idp.Issuer(),
+		"aud":   "chirpy",
+		"sub":   "user-1",
+		"nonce": "nonce-1",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+	token, _ := idp.SignIDToken(valid)
+	if _, err := provider.VerifyIDToken(ctx, token, "nonce-1"); err != nil {
+		t.Fatalf("valid id token rejected: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(ctx, token, "nonce-2"); err == nil {
+		t.Fatalf("id token accepted with the wrong nonce")
+	}
+
+	cases := map[string]jwt.MapClaims{
+		"wrong audience": {"aud": "someone-else"},
+		"wrong issuer":   {"iss": "https://evil.example.com"},
+		"expired":        {"exp": time.Now().Add(-time.Minute).Unix()},
+	}
+	for name, override := range cases {
+		claims := jwt.MapClaims{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		for k, v := range override {
+			claims[k] = v
+		}
+		token, _ := idp.SignIDToken(claims)
+		if _, err := provider.VerifyIDToken(ctx, token, "nonce-1"); err == nil {
+			t.Fatalf("id token accepted with %s", name)
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/crisp-coder/chirpy/internal/api"
+	"github.com/crisp-coder/chirpy/internal/auth"
 	"github.com/crisp-coder/chirpy/internal/config"
 	"github.com/crisp-coder/chirpy/internal/database"
+	"github.com/crisp-coder/chirpy/internal/mail"
+	"github.com/crisp-coder/chirpy/internal/oidc"
 	_ "github.com/lib/pq"
 )
 
@@ -20,6 +25,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.TOKEN_HASH_KEY == "" {
+		fmt.Println("TOKEN_HASH_KEY must be set in config")
+		os.Exit(1)
+	}
+
 	db, err := sql.Open("postgres", cfg.DB_URL)
 	if err != nil {
 		fmt.Println(err)
@@ -27,10 +37,54 @@ func main() {
 	}
 	dbQueries := database.New(db)
 
+	jwtKeys, err := loadJWTKeys(cfg)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	denylist, err := newDenylist(cfg, dbQueries)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	jwtKeys.UseDenylist(denylist)
+
+	passwordPolicy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	api_cfg := api.ApiConfig{
-		Db:         dbQueries,
-		JWT_SECRET: cfg.JWT_SECRET,
-		POLKA_KEY:  cfg.POLKA_KEY,
+		Db:             dbQueries,
+		SqlDB:          db,
+		JWTKeys:        jwtKeys,
+		Denylist:       denylist,
+		Passwords:      newPasswordHasher(cfg),
+		PasswordPolicy: passwordPolicy,
+		POLKA_KEY:      cfg.POLKA_KEY,
+		TOKEN_HASH_KEY: cfg.TOKEN_HASH_KEY,
+		APP_BASE_URL:   cfg.APP_BASE_URL,
+		PLATFORM:       cfg.PLATFORM,
+		Mailer:         mailer,
+		OIDC:           newOIDCProvider(cfg),
+
+		REQUIRE_VERIFIED_EMAIL: cfg.REQUIRE_VERIFIED_EMAIL,
+		ACCOUNT_DELETION_GRACE: api.DefaultAccountDeletionGrace,
+		CHIRP_EDIT_WINDOW:      api.DefaultChirpEditWindow,
+	}
+	if cfg.CHIRP_EDIT_WINDOW_MINUTES != 0 {
+		api_cfg.CHIRP_EDIT_WINDOW = time.Duration(cfg.CHIRP_EDIT_WINDOW_MINUTES) * time.Minute
+	}
+	if cfg.ACCOUNT_DELETION_GRACE_DAYS != 0 {
+		api_cfg.ACCOUNT_DELETION_GRACE = time.Duration(cfg.ACCOUNT_DELETION_GRACE_DAYS) * 24 * time.Hour
 	}
 
 	logFile, err := api.SetupLogging("application.log")
@@ -46,6 +100,8 @@ func main() {
 	}()
 	log.Println("log start")
 
+	go api_cfg.RunAccountDeletions(context.Background())
+
 	server := api.MakeServer(&api_cfg)
 	err = server.ListenAndServe()
 	if err != nil {
@@ -53,3 +109,106 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// loadJWTKeys builds the access token keyring from config. JWT_SECRET is kept
+// as a key without an id so tokens issued before asymmetric keys were
+// configured stay valid until they expire.
+func loadJWTKeys(cfg config.Config) (*auth.Keyring, error) {
+	keys := []*auth.SigningKey{}
+	if cfg.JWT_SECRET != "" {
+		keys = append(keys, auth.NewHMACKey("", cfg.JWT_SECRET))
+	}
+
+	for _, keyCfg := range cfg.JWT_SIGNING_KEYS {
+		if keyCfg.KID == "" {
+			return nil, fmt.Errorf("JWT_SIGNING_KEYS entries must have a KID")
+		}
+		key, err := auth.LoadSigningKey(keyCfg.KID, keyCfg.KEY_FILE)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return auth.NewKeyring(cfg.JWT_ACTIVE_KEY_ID, keys...)
+}
+
+func newDenylist(cfg config.Config, db *database.Queries) (auth.Denylist, error) {
+	switch cfg.DENYLIST {
+	case "db", "":
+		return auth.NewDBDenylist(db), nil
+	case "memory":
+		return auth.NewMemoryDenylist(), nil
+	default:
+		return nil, fmt.Errorf("unknown DENYLIST %q", cfg.DENYLIST)
+	}
+}
+
+func newPasswordHasher(cfg config.Config) *auth.PasswordHasher {
+	params := auth.DefaultArgon2Params
+	if cfg.ARGON2_MEMORY_KIB != 0 {
+		params.Memory = cfg.ARGON2_MEMORY_KIB
+	}
+	if cfg.ARGON2_ITERATIONS != 0 {
+		params.Iterations = cfg.ARGON2_ITERATIONS
+	}
+	if cfg.ARGON2_PARALLELISM != 0 {
+		params.Parallelism = cfg.ARGON2_PARALLELISM
+	}
+	return auth.NewPasswordHasher(params)
+}
+
+func newPasswordPolicy(cfg config.Config) (*auth.PasswordPolicy, error) {
+	minLength, maxLength := 12, 128
+	if cfg.PASSWORD_MIN_LENGTH != 0 {
+		minLength = cfg.PASSWORD_MIN_LENGTH
+	}
+	if cfg.PASSWORD_MAX_LENGTH != 0 {
+		maxLength = cfg.PASSWORD_MAX_LENGTH
+	}
+	policy := auth.NewPasswordPolicy(minLength, maxLength)
+
+	if cfg.PASSWORD_BANNED_FILE != "" {
+		err := policy.LoadBannedPasswords(cfg.PASSWORD_BANNED_FILE)
+		if err != nil {
+			return nil, fmt.Errorf("error loading banned passwords: %w", err)
+		}
+	}
+	if cfg.PASSWORD_BREACHED_DIR != "" {
+		breached, err := auth.NewBreachedPasswords(cfg.PASSWORD_BREACHED_DIR)
+		if err != nil {
+			return nil, fmt.Errorf("error opening breached passwords: %w", err)
+		}
+		policy.UseBreachedPasswords(breached)
+	}
+	return policy, nil
+}
+
+func newOIDCProvider(cfg config.Config) *oidc.Provider {
+	if cfg.OIDC_ISSUER == "" {
+		return nil
+	}
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.OIDC_ISSUER,
+		ClientID:     cfg.OIDC_CLIENT_ID,
+		ClientSecret: cfg.OIDC_CLIENT_SECRET,
+		RedirectURL:  cfg.OIDC_REDIRECT_URL,
+	}, nil)
+}
+
+func newMailer(cfg config.Config) (mail.Mailer, error) {
+	switch cfg.MAILER {
+	case "smtp":
+		return &mail.SMTPMailer{
+			Host:     cfg.SMTP_HOST,
+			Port:     cfg.SMTP_PORT,
+			Username: cfg.SMTP_USERNAME,
+			Password: cfg.SMTP_PASSWORD,
+			From:     cfg.MAIL_FROM,
+		}, nil
+	case "log", "":
+		return &mail.LogMailer{Path: cfg.MAIL_LOG_FILE, From: cfg.MAIL_FROM}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", cfg.MAILER)
+	}
+}
@@ -0,0 +1,30 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListChirpLikers :many
+SELECT sqlc.embed(users), chirp_likes.created_at AS liked_at
+FROM chirp_likes
+JOIN users ON users.id = chirp_likes.user_id
+WHERE chirp_likes.chirp_id = sqlc.arg('chirp_id')
+  AND users.deletion_requested_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirp_likes.created_at, chirp_likes.user_id) < (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirp_likes.created_at DESC, chirp_likes.user_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListLikedChirps :many
+SELECT sqlc.embed(chirps), chirp_likes.created_at AS liked_at
+FROM chirp_likes
+JOIN chirps ON chirps.id = chirp_likes.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE chirp_likes.user_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND users.deletion_requested_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirp_likes.created_at, chirp_likes.chirp_id) < (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirp_likes.created_at DESC, chirp_likes.chirp_id DESC
+LIMIT sqlc.arg('limit');
@@ -1,22 +1,180 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, root_chirp_id, rechirp_of_id, quote_of_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
--- name: GetChirps :many
-SELECT *
+-- name: ListChirpsAsc :many
+SELECT chirps.*
 FROM chirps
-ORDER BY created_at DESC;
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('limit');
 
--- name: GetChirpsByUserID :many
-SELECT *
+-- name: ListChirpsDesc :many
+SELECT chirps.*
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTimeline :many
+SELECT chirps.*
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('follower_id')
+  AND users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps), ts_rank(chirps.search_vector, query) AS rank
 FROM chirps
-WHERE user_id = $1
-ORDER BY created_at DESC;
+JOIN users ON users.id = chirps.user_id,
+    to_tsquery('english', sqlc.arg('query')) AS query
+WHERE users.deletion_requested_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND chirps.search_vector @@ query
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('cursor_rank')::real IS NULL OR (ts_rank(chirps.search_vector, query), chirps.id) < (sqlc.narg('cursor_rank'), sqlc.arg('cursor_id')::uuid))
+ORDER BY rank DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirp :one
+SELECT chirps.*
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL AND users.deletion_requested_at IS NULL;
+
+-- name: GetChirpForUpdate :one
 SELECT *
 FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: GetChirpWithAncestors :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.*, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('id')
+    UNION ALL
+    SELECT chirps.*, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.id = thread.parent_chirp_id
+    WHERE thread.depth < sqlc.arg('max_depth')::int
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.parent_chirp_id, thread.root_chirp_id, thread.quote_of_id,
+    COALESCE(thread.deleted_at, users.deletion_requested_at) AS deleted_at,
+    thread.depth
+FROM thread
+JOIN users ON users.id = thread.user_id
+ORDER BY thread.depth DESC;
+
+-- name: ListChirpReplies :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.root_chirp_id, chirps.quote_of_id,
+    COALESCE(chirps.deleted_at, users.deletion_requested_at) AS deleted_at,
+    EXISTS (
+        SELECT 1
+        FROM chirps AS replies
+        WHERE replies.parent_chirp_id = chirps.id
+    ) AS has_replies
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.parent_chirp_id = sqlc.arg('parent_chirp_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.*, 1 AS depth
+    FROM chirps
+    WHERE chirps.parent_chirp_id = ANY(sqlc.arg('parent_ids')::uuid[])
+    UNION ALL
+    SELECT chirps.*, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.parent_chirp_id = thread.id
+    WHERE thread.depth < sqlc.arg('max_depth')::int
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.parent_chirp_id, thread.root_chirp_id, thread.quote_of_id,
+    COALESCE(thread.deleted_at, users.deletion_requested_at) AS deleted_at,
+    EXISTS (
+        SELECT 1
+        FROM chirps AS replies
+        WHERE replies.parent_chirp_id = thread.id
+    ) AS has_replies
+FROM thread
+JOIN users ON users.id = thread.user_id
+ORDER BY thread.depth, thread.created_at, thread.id
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: GetChirpCounts :many
+SELECT chirps.id,
+    (
+        SELECT COUNT(*)
+        FROM chirp_likes
+        JOIN users ON users.id = chirp_likes.user_id
+        WHERE chirp_likes.chirp_id = chirps.id AND users.deletion_requested_at IS NULL
+    ) AS like_count,
+    EXISTS (
+        SELECT 1
+        FROM chirp_likes
+        WHERE chirp_likes.chirp_id = chirps.id AND chirp_likes.user_id = sqlc.arg('viewer_id')
+    ) AS liked_by_me,
+    (
+        SELECT COUNT(*)
+        FROM chirps AS rechirps
+        JOIN users ON users.id = rechirps.user_id
+        WHERE rechirps.rechirp_of_id = chirps.id AND users.deletion_requested_at IS NULL
+    ) AS rechirp_count,
+    (
+        SELECT COUNT(*)
+        FROM chirps AS quotes
+        JOIN users ON users.id = quotes.user_id
+        WHERE quotes.quote_of_id = chirps.id AND quotes.deleted_at IS NULL AND users.deletion_requested_at IS NULL
+    ) AS quote_count
+FROM chirps
+WHERE chirps.id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: ChirpHasRepliesOrQuotes :one
+SELECT EXISTS (
+    SELECT 1
+    FROM chirps
+    WHERE parent_chirp_id = $1 OR quote_of_id = $1
+);
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of_id = $1;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE rechirp_of_id = $1 AND user_id = $2;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', updated_at = $2, deleted_at = $2
 WHERE id = $1;
 
 -- name: ResetChirps :exec
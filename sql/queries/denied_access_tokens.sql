@@ -0,0 +1,15 @@
+-- name: DenyAccessToken :exec
+INSERT INTO denied_access_tokens (token_id, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (token_id) DO NOTHING;
+
+-- name: IsAccessTokenDenied :one
+SELECT EXISTS (
+    SELECT 1
+    FROM denied_access_tokens
+    WHERE token_id = $1
+);
+
+-- name: DeleteExpiredDeniedAccessTokens :exec
+DELETE FROM denied_access_tokens
+WHERE expires_at <= $1;
@@ -0,0 +1,43 @@
+-- name: UpsertHashtag :one
+INSERT INTO hashtags (id, tag, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+RETURNING *;
+
+-- name: AddChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (chirp_id, hashtag_id) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: ListHashtagChirps :many
+SELECT chirps.*
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE hashtags.tag = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND users.deletion_requested_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirp_hashtags.created_at, chirp_hashtags.chirp_id) < (sqlc.narg('cursor_created_at'), sqlc.arg('cursor_id')::uuid))
+ORDER BY chirp_hashtags.created_at DESC, chirp_hashtags.chirp_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTrendingHashtags :many
+SELECT hashtags.tag,
+    COUNT(*) AS chirp_count,
+    SUM(POWER(0.5, EXTRACT(EPOCH FROM (sqlc.arg('now')::timestamp - chirp_hashtags.created_at)) / sqlc.arg('half_life_seconds')::float8))::float8 AS score
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE chirp_hashtags.created_at > sqlc.arg('since')
+  AND chirp_hashtags.created_at <= sqlc.arg('now')
+  AND chirps.deleted_at IS NULL
+  AND users.deletion_requested_at IS NULL
+GROUP BY hashtags.tag
+ORDER BY score DESC, hashtags.tag
+LIMIT sqlc.arg('limit');
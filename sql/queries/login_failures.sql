@@ -0,0 +1,29 @@
+-- name: GetLoginFailure :one
+SELECT *
+FROM login_failures
+WHERE email = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (email, failed_login_count, last_failed_at)
+VALUES (sqlc.arg('email'), 1, sqlc.arg('failed_at'))
+ON CONFLICT (email) DO UPDATE
+SET failed_login_count = CASE
+        WHEN login_failures.last_failed_at >= sqlc.arg('window_start')::timestamp OR login_failures.locked_until >= sqlc.arg('window_start')::timestamp
+        THEN login_failures.failed_login_count + 1
+        ELSE 1
+    END,
+    last_failed_at = sqlc.arg('failed_at')
+RETURNING failed_login_count;
+
+-- name: LockLoginFailure :exec
+UPDATE login_failures
+SET locked_until = $2
+WHERE email = $1;
+
+-- name: DeleteExpiredLoginFailures :exec
+DELETE FROM login_failures
+WHERE last_failed_at < sqlc.arg('window_start')
+  AND (locked_until IS NULL OR locked_until < sqlc.arg('window_start'));
+
+-- name: ResetLoginFailures :exec
+DELETE FROM login_failures;
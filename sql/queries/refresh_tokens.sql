@@ -1,22 +1,59 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, session_started_at, user_agent, ip_address, access_token_id, access_token_expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 RETURNING *;
 
--- name: GetRefreshTokenByUserID :one
+-- name: GetActiveRefreshTokensByUserID :many
 SELECT *
 FROM refresh_tokens
-WHERE user_id = $1;
+WHERE user_id = $1 AND revoked_at IS NULL AND rotated_at IS NULL AND expires_at > $2
+ORDER BY created_at DESC;
 
--- name: GetRefreshTokenByToken :one
+-- name: GetLiveAccessTokensByFamilyID :many
 SELECT *
 FROM refresh_tokens
-WHERE token = $1;
+WHERE family_id = $1 AND access_token_id <> '' AND access_token_expires_at > $2;
+
+-- name: GetOtherLiveAccessTokensByUserID :many
+SELECT *
+FROM refresh_tokens
+WHERE user_id = $1 AND family_id <> $2 AND access_token_id <> '' AND access_token_expires_at > $3;
+
+-- name: GetRefreshTokenByHash :one
+SELECT *
+FROM refresh_tokens
+WHERE token_hash = $1;
 
 -- name: SetRefreshTokenRevoked :exec
 UPDATE refresh_tokens
 SET updated_at = $2, revoked_at = $3
-WHERE token = $1;
+WHERE token_hash = $1;
+
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET updated_at = $2, rotated_at = $3
+WHERE token_hash = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = $2, revoked_at = $3
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamilyForUser :execrows
+UPDATE refresh_tokens
+SET updated_at = $3, revoked_at = $4
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensByUserID :exec
+UPDATE refresh_tokens
+SET updated_at = $2, revoked_at = $3
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherRefreshTokensByUserID :exec
+UPDATE refresh_tokens
+SET updated_at = $3, revoked_at = $4
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL;
 
 -- name: ResetRefreshTokens :exec
 DELETE FROM refresh_tokens;
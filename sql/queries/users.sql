@@ -1,6 +1,22 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetUserByHandle :one
+SELECT *
+FROM users
+WHERE handle = $1 AND deletion_requested_at IS NULL;
+
+-- name: GetUsersByIDs :many
+SELECT *
+FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deletion_requested_at IS NULL;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = $2, handle = $3, display_name = $4, bio = $5
+WHERE id = $1
 RETURNING *;
 
 -- name: GetUserByEmail :one
@@ -18,10 +34,93 @@ DELETE FROM users;
 
 -- name: UpdateUser :one
 UPDATE users
-SET updated_at = $2, email = $3, hashed_password = $4
+SET updated_at = $2, email = $3, hashed_password = $4,
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateUserEmail :one
+UPDATE users
+SET updated_at = $2, email = $3,
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET updated_at = $2, hashed_password = $3
+WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET updated_at = $2, role = $3
+WHERE id = $1;
+
+-- name: SetUserSuspended :execrows
+UPDATE users
+SET updated_at = $2, suspended_at = $3
+WHERE id = $1;
+
+-- name: RequestUserDeletion :exec
+UPDATE users
+SET updated_at = $2, deletion_requested_at = $3
+WHERE id = $1;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET updated_at = $2, deletion_requested_at = NULL
+WHERE id = $1;
+
+-- name: DeleteUsersPendingDeletion :execrows
+DELETE FROM users
+WHERE deletion_requested_at <= $1;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET updated_at = $2, totp_secret = $3, totp_enabled_at = NULL, totp_last_used_step = NULL
+WHERE id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET updated_at = $2, totp_enabled_at = $3
+WHERE id = $1;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET updated_at = $2, totp_secret = NULL, totp_enabled_at = NULL, totp_last_used_step = NULL
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_used_step = $2
+WHERE id = $1 AND (totp_last_used_step IS NULL OR totp_last_used_step < $2);
+
+-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_count = CASE
+        WHEN last_failed_login_at >= sqlc.arg('window_start')::timestamp OR locked_until >= sqlc.arg('window_start')::timestamp
+        THEN failed_login_count + 1
+        ELSE 1
+    END,
+    last_failed_login_at = sqlc.arg('failed_at')::timestamp
+WHERE id = sqlc.arg('id')
+RETURNING failed_login_count;
+
+-- name: LockUser :exec
+UPDATE users
+SET locked_until = $2
+WHERE id = $1;
+
+-- name: UnlockUser :execrows
+UPDATE users
+SET failed_login_count = 0, locked_until = NULL
+WHERE id = $1;
+
+-- name: VerifyUserEmail :execrows
+UPDATE users
+SET updated_at = $2, email_verified_at = $3
+WHERE id = $1 AND email = $4;
+
 -- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = true
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Refresh tokens were stored in plaintext. They cannot be converted here
+-- because hashing needs the server's TOKEN_HASH_KEY, so existing tokens are
+-- dropped and users have to log in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+-- +goose Down
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
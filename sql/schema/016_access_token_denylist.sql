@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN access_token_id TEXT NOT NULL DEFAULT '',
+ADD COLUMN access_token_expires_at TIMESTAMP;
+
+CREATE TABLE denied_access_tokens (
+    token_id TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN suspended_at;
+
+DROP TABLE denied_access_tokens;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN access_token_expires_at,
+DROP COLUMN access_token_id;
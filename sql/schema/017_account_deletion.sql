@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN deletion_requested_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN deletion_requested_at;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Failed logins for emails with no account, counted the same way as
+-- users.failed_login_count so a lockout doesn't reveal which emails are
+-- registered.
+CREATE TABLE login_failures (
+    email TEXT PRIMARY KEY,
+    failed_login_count INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_failures;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Failed login counts start again once an account, or an email without one,
+-- has gone a while without failing or being locked.
+ALTER TABLE users
+ADD COLUMN last_failed_login_at TIMESTAMP;
+
+ALTER TABLE login_failures
+ADD COLUMN last_failed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+ALTER TABLE login_failures
+ALTER COLUMN last_failed_at DROP DEFAULT;
+
+CREATE INDEX login_failures_last_failed_at_idx ON login_failures (last_failed_at);
+
+-- +goose Down
+DROP INDEX login_failures_last_failed_at_idx;
+
+ALTER TABLE login_failures
+DROP COLUMN last_failed_at;
+
+ALTER TABLE users
+DROP COLUMN last_failed_login_at;